@@ -3,6 +3,7 @@ package doc2txt
 import (
 	"encoding/binary"
 	"errors"
+	"sort"
 
 	"github.com/richardlehane/mscfb"
 )
@@ -11,10 +12,13 @@ var (
 	errInvalidPrc  = errors.New("Invalid Prc structure")
 	errInvalidClx  = errors.New("expected last aCP value to equal fib.cpLength (2.8.35)")
 	errInvalidPcdt = errors.New("expected clxt to be equal 0x02")
+	errInvalidText = errors.New("expected text between fcMin and fcMac")
 )
 
 type clx struct {
-	pcdt pcdt
+	rgPrc [][]byte // the grpprl of each Prc
+	pcdt  pcdt
+	word6 bool // the Prms and Prcs use the one byte sprms of Word 6.0 and Word 95
 }
 
 type pcdt struct {
@@ -28,7 +32,8 @@ type plcPcd struct {
 }
 
 type pcd struct {
-	fc fcCompressed
+	fc  fcCompressed
+	prm uint16
 }
 
 type fcCompressed struct {
@@ -41,12 +46,15 @@ func getClx(table *mscfb.File, fib *fib) (*clx, error) {
 	if table == nil || fib == nil {
 		return nil, errInvalidArgument
 	}
+	if !fib.base.fComplex && fib.fibRgFcLcb.lcbClx == 0 {
+		return getNonComplexClx(fib)
+	}
 	b, err := readClx(table, fib)
 	if err != nil {
 		return nil, err
 	}
 
-	pcdtOffset, err := getPrcArrayEnd(b)
+	rgPrc, pcdtOffset, err := getRgPrc(b)
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +67,25 @@ func getClx(table *mscfb.File, fib *fib) (*clx, error) {
 	if pcdt.PlcPcd.aCP[len(pcdt.PlcPcd.aCP)-1] != fib.fibRgLw.cpLength {
 		return nil, errInvalidClx
 	}
+	if fib.isWord6() {
+		// Word 6.0 and Word 95 text is always 8-bit and fc is the byte offset, so store it the way a compressed fc is stored
+		for i := range pcdt.PlcPcd.aPcd {
+			pcdt.PlcPcd.aPcd[i].fc = fcCompressed{fc: pcdt.PlcPcd.aPcd[i].fc.fc * 2, fCompressed: true}
+		}
+	}
+
+	return &clx{rgPrc: rgPrc, pcdt: *pcdt, word6: fib.isWord6()}, nil
+}
 
-	return &clx{pcdt: *pcdt}, nil
+// files that were never saved incrementally may have no Clx. Their text is stored in order as 8-bit
+// characters from fcMin to fcMac, so it is described with a single compressed piece
+func getNonComplexClx(fib *fib) (*clx, error) {
+	cpLength := fib.fibRgLw.cpLength
+	if cpLength <= 0 || fib.base.fcMin <= 0 || fib.base.fcMin+cpLength > fib.base.fcMac {
+		return nil, errInvalidText
+	}
+	aPcd := []pcd{{fc: fcCompressed{fc: fib.base.fcMin * 2, fCompressed: true}}}
+	return &clx{pcdt: pcdt{PlcPcd: plcPcd{aCP: []int{0, cpLength}, aPcd: aPcd}}}, nil
 }
 
 func readClx(table *mscfb.File, fib *fib) ([]byte, error) {
@@ -98,28 +123,43 @@ func getPcdt(clx []byte, pcdtOffset int) (*pcdt, error) {
 	return &pcdt{lcb: lcb, PlcPcd: plcPcd{aCP: cps, aPcd: pcds}}, nil
 }
 
-// find end of RgPrc array (section 2.9.38)
-func getPrcArrayEnd(clx []byte) (int, error) {
+// read the grpprl of each Prc in the RgPrc array and find where the array ends (section 2.9.38)
+func getRgPrc(clx []byte) ([][]byte, int, error) {
+	var rgPrc [][]byte
 	prcOffset := 0
-	count := 0
 	for {
 		clxt := clx[prcOffset]
 		if clxt != 0x01 { // this is not a Prc, so exit
-			return prcOffset, nil
+			return rgPrc, prcOffset, nil
 		}
 		prcDataCbGrpprl := binary.LittleEndian.Uint16(clx[prcOffset+1 : prcOffset+3]) // skip the clxt and read 2 bytes
-		prcOffset += 1 + 2 + int(prcDataCbGrpprl)                                     // skip clxt, cbGrpprl, and GrpPrl
+		grpprlStart := prcOffset + 3
+		prcOffset += 1 + 2 + int(prcDataCbGrpprl) // skip clxt, cbGrpprl, and GrpPrl
 
-		if count > 10000 || prcDataCbGrpprl <= 0 || prcOffset+3 > len(clx) { // ensure no infinite loop
-			return 0, errInvalidPrc
+		if len(rgPrc) > 10000 || prcDataCbGrpprl <= 0 || prcOffset+3 > len(clx) { // ensure no infinite loop
+			return nil, 0, errInvalidPrc
 		}
-		count++
+		rgPrc = append(rgPrc, clx[grpprlStart:prcOffset])
+	}
+}
+
+// find the offset in the WordDocument stream of the character at cp and whether it is compressed (section 2.4.1)
+func (c *clx) fcForCP(cp int) (int, bool, bool) {
+	aCP := c.pcdt.PlcPcd.aCP
+	i := sort.Search(len(aCP), func(i int) bool { return aCP[i] > cp }) - 1
+	if i < 0 || i >= len(c.pcdt.PlcPcd.aPcd) {
+		return 0, false, false
+	}
+	fc := c.pcdt.PlcPcd.aPcd[i].fc
+	if fc.fCompressed {
+		return fc.fc/2 + cp - aCP[i], true, true
 	}
+	return fc.fc + 2*(cp-aCP[i]), false, true
 }
 
 // parse Pcd (section 2.9.177)
 func parsePcd(pcdData []byte) *pcd {
-	return &pcd{fc: *parseFcCompressed(pcdData[2:6])}
+	return &pcd{fc: *parseFcCompressed(pcdData[2:6]), prm: binary.LittleEndian.Uint16(pcdData[6:8])}
 }
 
 // parse FcCompressed (section 2.9.73)
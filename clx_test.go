@@ -54,25 +54,25 @@ func TestGetClx(t *testing.T) {
 	}
 }
 
-func TestGetPrcArrayEnd(t *testing.T) {
+func TestGetRgPrc(t *testing.T) {
 	//skip since it is not a Prc
 	clx := []byte{2, 0, 0, 0}
-	if num, _ := getPrcArrayEnd(clx); num != 0 {
+	if _, num, _ := getRgPrc(clx); num != 0 {
 		t.Error("expected to be set to beginning")
 	}
 	// error due to zero offset with valid Prc clxt
 	clx = []byte{1, 0, 0, 0}
-	if _, err := getPrcArrayEnd(clx); err != errInvalidPrc {
+	if _, _, err := getRgPrc(clx); err != errInvalidPrc {
 		t.Error("expected to revert to 0 due to invalid value", err)
 	}
 	// error since next offset would be too large
 	clx = []byte{1, 4, 4, 0}
-	if _, err := getPrcArrayEnd(clx); err != errInvalidPrc {
+	if _, _, err := getRgPrc(clx); err != errInvalidPrc {
 		t.Error("expected to revert to 0 due to invalid value", err)
 	}
 	// two items
 	clx = []byte{1, 2, 0, 0, 0, 1, 2, 0, 0, 2, 2, 2, 2}
-	if num, err := getPrcArrayEnd(clx); err != nil || num != 10 {
-		t.Error("expected to revert to 0 due to invalid value", err, num)
+	if rgPrc, num, err := getRgPrc(clx); err != nil || num != 10 || len(rgPrc) != 2 || len(rgPrc[0]) != 2 || rgPrc[1][1] != 2 {
+		t.Error("expected to revert to 0 due to invalid value", err, num, rgPrc)
 	}
 }
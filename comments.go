@@ -0,0 +1,147 @@
+package doc2txt
+
+import (
+	"strings"
+	"time"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// read the names of the authors of revisions from the SttbfRMark (section 2.9.290)
+func getRevisionAuthors(table *mscfb.File, fib *fib) ([]string, error) {
+	b, err := readBlock(table, fib.fibRgFcLcb.fcSttbfRMark, fib.fibRgFcLcb.lcbSttbfRMark)
+	if err != nil {
+		return nil, err
+	}
+	authors, _, err := parseSttb(b)
+	return authors, err
+}
+
+// read the names of the authors of comments from the GrpXstAtnOwners, which is an array of Xst
+func getCommentAuthors(table *mscfb.File, fib *fib) ([]string, error) {
+	b, err := readBlock(table, fib.fibRgFcLcb.fcGrpXstAtnOwners, fib.fibRgFcLcb.lcbGrpXstAtnOwners)
+	if err != nil {
+		return nil, err
+	}
+	var authors []string
+	for offset := 0; offset+2 <= len(b); {
+		author, n := parseXst(b[offset:])
+		authors = append(authors, author)
+		offset += n
+	}
+	return authors, nil
+}
+
+// find the comments using the PlcfandRef and PlcfandTxt (sections 2.8.7 and 2.8.8)
+func (b *docBuilder) comments() error {
+	fc := b.w.fib.fibRgFcLcb
+	refs, err := getPlc(b.w.table, fc.fcPlcfandRef, fc.lcbPlcfandRef, 30)
+	if err != nil {
+		return err
+	}
+	txt, err := getPlc(b.w.table, fc.fcPlcfandTxt, fc.lcbPlcfandTxt, 0)
+	if err != nil {
+		return err
+	}
+	authors, err := getCommentAuthors(b.w.table, b.w.fib)
+	if err != nil {
+		return err
+	}
+
+	story := b.story(StoryComments)
+	for i, atrd := range refs.aData {
+		c := Comment{CP: refs.aCP[i]}
+
+		// ATRDPre10 (section 2.9.7) starts with the initials in an LPXCharBuffer9 (section 2.9.144)
+		if cch := getInt16(atrd, 0); cch <= 9 {
+			c.Initials = decodeUTF16(atrd[2 : 2+cch*2])
+		}
+		if ibst := getInt16(atrd, 20); ibst < len(authors) {
+			c.Author = authors[ibst]
+		}
+		if story != nil && i+1 < len(txt.aCP) {
+			c.Text = storyText(story, story.CP+txt.aCP[i], story.CP+txt.aCP[i+1])
+		}
+		b.doc.Comments = append(b.doc.Comments, c)
+	}
+	return nil
+}
+
+func (b *docBuilder) story(typ StoryType) *Story {
+	for i := range b.doc.Stories {
+		if b.doc.Stories[i].Type == typ {
+			return &b.doc.Stories[i]
+		}
+	}
+	return nil
+}
+
+// the text of the paragraphs in the story that begin between start and end, one paragraph per line
+func storyText(story *Story, start, end int) string {
+	var lines []string
+	addParagraph := func(p *Paragraph) {
+		if p.CP >= start && p.CP < end {
+			lines = append(lines, p.Text)
+		}
+	}
+	for _, section := range story.Sections {
+		for _, block := range section.Blocks {
+			if block.Paragraph != nil {
+				addParagraph(block.Paragraph)
+				continue
+			}
+			for _, row := range block.Table.Rows {
+				for _, cell := range row.Cells {
+					for i := range cell.Paragraphs {
+						addParagraph(&cell.Paragraphs[i])
+					}
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// record the revision marks of a run. cpEnd is the CP after the last character of the run
+func (b *docBuilder) addRevision(typ StoryType, run *Run, cpEnd int, c chp) {
+	if c.rmarkIns {
+		b.appendRevision(Revision{Type: RevisionInsertion, Story: typ, CP: run.CP, Length: cpEnd - run.CP,
+			Author: b.author(c.ibstRMark), Date: parseDTTM(c.dttmRMark), Text: run.Text})
+	}
+	if c.rmarkDel {
+		b.appendRevision(Revision{Type: RevisionDeletion, Story: typ, CP: run.CP, Length: cpEnd - run.CP,
+			Author: b.author(c.ibstRMarkDel), Date: parseDTTM(c.dttmRMarkDel), Text: run.Text})
+	}
+}
+
+// add a revision, merging it with the previous one if it continues it, including across a paragraph mark
+func (b *docBuilder) appendRevision(r Revision) {
+	revisions := b.doc.Revisions
+	if n := len(revisions); n > 0 {
+		last := &revisions[n-1]
+		gap := r.CP - (last.CP + last.Length)
+		if last.Type == r.Type && last.Story == r.Story && last.Author == r.Author && sameTime(last.Date, r.Date) && gap >= 0 && gap <= 1 {
+			if gap == 1 {
+				last.Text += "\n"
+			}
+			last.Text += r.Text
+			last.Length = r.CP + r.Length - last.CP
+			return
+		}
+	}
+	b.doc.Revisions = append(revisions, r)
+}
+
+func (b *docBuilder) author(ibst int) string {
+	if ibst >= 0 && ibst < len(b.authors) {
+		return b.authors[ibst]
+	}
+	return ""
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
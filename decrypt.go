@@ -0,0 +1,302 @@
+package doc2txt
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// ErrIncorrectPassword is returned when none of the passwords supplied with WithPassword open an encrypted document
+var ErrIncorrectPassword = errors.New("incorrect password")
+
+var (
+	errInvalidEncryptionHeader = errors.New("invalid encryption header")
+)
+
+const (
+	encryptionBlockSize = 512 // the RC4 key changes every 512 bytes (section 2.2.6.2)
+	fibUnencryptedSize  = 68  // the start of the WordDocument stream is never encrypted (section 2.2.6)
+	rc4HeaderSize       = 52  // the size of the RC4 EncryptionHeader ([MS-OFFCRYPTO] section 2.3.6.1)
+)
+
+// blockKey returns the RC4 key for a 512-byte block of a stream
+type blockKey func(block uint32) []byte
+
+// decrypt the WordDocument, table and Data streams of an encrypted document into a copy of the
+// compound file in memory, using the first of the passwords that is correct (section 2.2.6). Property
+// set streams that were encrypted with RC4 CryptoAPI are returned decrypted by name
+func decrypt(ra io.ReaderAt, fib *fib, wordDoc, table *mscfb.File, passwords []string) (*mscfb.Reader, map[string][]byte, error) {
+	if fib.base.fObfuscated || fib.isWord6() { // Word 6.0 and Word 95 only have XOR obfuscation, which has no flag in their FIB
+		d, err := deobfuscate(ra, fib, wordDoc, passwords)
+		return d, nil, err
+	}
+	if len(passwords) == 0 {
+		return nil, nil, getEncryptionError(fib, table)
+	}
+	header, err := readBlock(table, 0, fib.base.lKey)
+	if err != nil || len(header) < 4 {
+		return nil, nil, errInvalidEncryptionHeader
+	}
+
+	kind := encryptionKind(getInt16(header, 0), getInt16(header, 2))
+	var keys func(string) (blockKey, error)
+	switch kind {
+	case EncryptionRC4:
+		keys = func(password string) (blockKey, error) { return rc4Keys(header, password) }
+	case EncryptionRC4CryptoAPI:
+		keys = func(password string) (blockKey, error) { return cryptoAPIKeys(header, password) }
+	default:
+		return nil, nil, getEncryptionError(fib, table)
+	}
+
+	for _, password := range passwords {
+		key, err := keys(password)
+		if err == ErrIncorrectPassword {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		d, err := transformStreams(ra, fib, fib.base.lKey, func(b []byte) {
+			for i := 0; i < len(b); i += encryptionBlockSize {
+				end := i + encryptionBlockSize
+				if end > len(b) {
+					end = len(b)
+				}
+				c, _ := rc4.NewCipher(key(uint32(i / encryptionBlockSize)))
+				c.XORKeyStream(b[i:end], b[i:end])
+			}
+		})
+		if err != nil || kind != EncryptionRC4CryptoAPI {
+			return d, nil, err
+		}
+		return d, decryptSummary(d, key), nil
+	}
+	return nil, nil, ErrIncorrectPassword
+}
+
+// read the streams in the encrypted summary stream, which holds the property set streams of a document
+// when they are encrypted with RC4 CryptoAPI ([MS-OFFCRYPTO] section 2.3.5.4). The offset and size of
+// the StreamDescriptorArray, and the array itself, are each decrypted with a new cipher for block 0,
+// and each stream with a new cipher for its own block. Streams that cannot be read are left out
+func decryptSummary(d *mscfb.Reader, key blockKey) map[string][]byte {
+	stream := getStream(d, "encryption")
+	if stream == nil {
+		return nil
+	}
+	b, err := readBlock(stream, 0, int(stream.Size))
+	if err != nil || len(b) < 8 {
+		return nil
+	}
+	location := rc4Decrypt(key(0), b[:8])
+	offset, size := getInt(location, 0), getInt(location, 4)
+	if offset < 8 || offset > len(b) || size < 4 || size > len(b)-offset {
+		return nil
+	}
+	array := rc4Decrypt(key(0), b[offset:offset+size])
+
+	streams := map[string][]byte{}
+	count := getInt(array, 0)
+	for i, pos := 0, 4; i < count && pos+16 <= len(array); i++ {
+		// StreamDescriptor: StreamOffset, StreamSize, Block, NameSize, flags, Reserved2 and StreamName
+		streamOffset, streamSize := getInt(array, pos), getInt(array, pos+4)
+		block, nameSize := getInt16(array, pos+8), int(array[pos+10])
+		nameEnd := pos + 16 + nameSize*2
+		if nameEnd+2 > len(array) {
+			break
+		}
+		name := strings.TrimPrefix(decodeUTF16(array[pos+16:nameEnd]), "\x05") // named as mscfb names the streams
+		pos = nameEnd + 2
+		if streamOffset > len(b) || streamSize > len(b)-streamOffset {
+			continue
+		}
+		streams[name] = rc4Decrypt(key(uint32(block)), b[streamOffset:streamOffset+streamSize])
+	}
+	return streams
+}
+
+// decrypt b with a new RC4 cipher
+func rc4Decrypt(key, b []byte) []byte {
+	out := make([]byte, len(b))
+	if c, err := rc4.NewCipher(key); err == nil {
+		c.XORKeyStream(out, b)
+	}
+	return out
+}
+
+// copy the compound file into memory and transform the content of the WordDocument, table and Data
+// streams with fn. The parts of the streams that are stored unencrypted, including the first
+// tableUnencrypted bytes of the table stream, are restored afterwards
+func transformStreams(ra io.ReaderAt, fib *fib, tableUnencrypted int, fn func(b []byte)) (*mscfb.Reader, error) {
+	b, err := ioutil.ReadAll(io.NewSectionReader(ra, 0, math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	mem := &memoryFile{b: b}
+	d, err := newCompoundFile(mem)
+	if err != nil {
+		return nil, err
+	}
+
+	wordDoc, table0, table1 := getWordDocAndTables(d)
+	wordDocUnencrypted, table := fibUnencryptedSize, getActiveTable(wordDoc, table0, table1, fib)
+	if fib.isWord6() { // the structures are in the WordDocument stream, which has a shorter FIB
+		wordDocUnencrypted, table = fibWord6UnencryptedSize, nil
+	}
+	streams := []struct {
+		file        *mscfb.File
+		unencrypted int
+	}{
+		{wordDoc, wordDocUnencrypted},
+		{table, tableUnencrypted},
+		{getStream(d, "Data"), 0},
+	}
+	for _, stream := range streams {
+		if stream.file == nil || stream.file.Size == 0 {
+			continue
+		}
+		content, err := readBlock(stream.file, 0, int(stream.file.Size))
+		if err != nil {
+			return nil, err
+		}
+		plain := make([]byte, stream.unencrypted)
+		copy(plain, content)
+		fn(content)
+		copy(content, plain)
+		if _, err := stream.file.WriteAt(content, 0); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// the RC4 keys for a password, checking it against the password verifier ([MS-OFFCRYPTO] section 2.3.6)
+func rc4Keys(header []byte, password string) (blockKey, error) {
+	if len(header) < rc4HeaderSize {
+		return nil, errInvalidEncryptionHeader
+	}
+	salt, verifier, verifierHash := header[4:20], header[20:36], header[36:52]
+
+	// the intermediate key is derived from the first 40 bits of the password hash and the salt (section 2.3.6.2)
+	h0 := md5.Sum(passwordBytes(password))
+	var buf []byte
+	for i := 0; i < 16; i++ {
+		buf = append(append(buf, h0[:5]...), salt...)
+	}
+	h1 := md5.Sum(buf)
+	truncated := h1[:5]
+	key := func(block uint32) []byte {
+		h := md5.Sum(append(append([]byte{}, truncated...), uint32Bytes(block)...))
+		return h[:]
+	}
+
+	if !checkVerifier(key(0), verifier, verifierHash, func(b []byte) []byte { h := md5.Sum(b); return h[:] }) {
+		return nil, ErrIncorrectPassword
+	}
+	return key, nil
+}
+
+// the RC4 CryptoAPI keys for a password, checking it against the password verifier ([MS-OFFCRYPTO] section 2.3.5)
+func cryptoAPIKeys(header []byte, password string) (blockKey, error) {
+	if len(header) < 12 {
+		return nil, errInvalidEncryptionHeader
+	}
+	headerSize := getInt(header, 8)
+	offset := 12 + headerSize // EncryptionVerifier follows the EncryptionHeader
+	if headerSize < 32 || offset+4 > len(header) {
+		return nil, errInvalidEncryptionHeader
+	}
+	keySize := getInt(header, 12+16) // in bits. Zero means 40 bits
+	saltSize := getInt(header, offset)
+	if saltSize != 16 || offset+4+16+16+4 > len(header) {
+		return nil, errInvalidEncryptionHeader
+	}
+	salt := header[offset+4 : offset+20]
+	verifier := header[offset+20 : offset+36]
+	hashSize := getInt(header, offset+36)
+	if offset+40+hashSize > len(header) {
+		return nil, errInvalidEncryptionHeader
+	}
+	verifierHash := header[offset+40 : offset+40+hashSize]
+
+	// the key is the hash of the salted password hash and the block number (section 2.3.5.2)
+	h0 := sha1.Sum(append(append([]byte{}, salt...), passwordBytes(password)...))
+	key := func(block uint32) []byte {
+		h := sha1.Sum(append(append([]byte{}, h0[:]...), uint32Bytes(block)...))
+		if keySize == 0 || keySize == 40 { // 40-bit keys are padded to 128 bits with zeros
+			return append(h[:5], make([]byte, 11)...)
+		}
+		if keySize/8 < len(h) {
+			return h[:keySize/8]
+		}
+		return h[:]
+	}
+
+	if !checkVerifier(key(0), verifier, verifierHash, func(b []byte) []byte { h := sha1.Sum(b); return h[:] }) {
+		return nil, ErrIncorrectPassword
+	}
+	return key, nil
+}
+
+// decrypt the verifier and its hash with the key for block 0, and check that the hash matches ([MS-OFFCRYPTO] section 2.3.5.6)
+func checkVerifier(key, verifier, verifierHash []byte, hash func([]byte) []byte) bool {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return false
+	}
+	v := make([]byte, len(verifier))
+	c.XORKeyStream(v, verifier)
+	h := make([]byte, len(verifierHash))
+	c.XORKeyStream(h, verifierHash)
+	expected := hash(v)
+	return len(h) >= len(expected) && bytes.Equal(h[:len(expected)], expected)
+}
+
+// passwords are hashed as UTF-16 without a terminating null
+func passwordBytes(password string) []byte {
+	u := utf16.Encode([]rune(password))
+	b := make([]byte, len(u)*2)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return b
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+// memoryFile is a compound file held in memory that can be written to, so streams can be decrypted in place
+type memoryFile struct {
+	b []byte
+}
+
+func (m *memoryFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *memoryFile) WriteAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > int64(len(m.b)) {
+		return 0, io.ErrShortWrite
+	}
+	return copy(m.b[off:], p), nil
+}
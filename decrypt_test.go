@@ -0,0 +1,300 @@
+package doc2txt
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// the salt and verifier of the encrypted test documents
+var (
+	testSalt     = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	testVerifier = []byte("doc2txt verifier")
+)
+
+// make an encrypted copy of docFile.doc. The header is written over the style sheet at the start of
+// the table stream, so the style sheet is removed from the FIB. The streams are encrypted in blocks
+// of 512 bytes, each with a new cipher keyed for its block. When summary is set, the
+// SummaryInformation stream is replaced by an encrypted summary stream holding it
+func encryptTestDoc(t *testing.T, header []byte, key func(uint32) []byte, summary []byte) []byte {
+	b, err := ioutil.ReadFile(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := &memoryFile{b: b}
+	doc, _ := mscfb.New(mem)
+	wordDoc, _, table := getWordDocAndTables(doc)
+	data := getStream(doc, "Data")
+	summaryInformation := getStream(doc, "SummaryInformation")
+
+	flags := make([]byte, 1)
+	wordDoc.ReadAt(flags, 11)
+	wordDoc.WriteAt([]byte{flags[0] | 0x01}, 11) // fEncrypted
+	wordDoc.WriteAt(uint32Bytes(uint32(len(header))), 14)
+	wordDoc.WriteAt(uint32Bytes(0), 154+3*4) // lcbStshf
+	table.WriteAt(header, 0)
+
+	for _, stream := range []struct {
+		file        *mscfb.File
+		unencrypted int
+	}{{wordDoc, 0x44}, {table, len(header)}, {data, 0}} {
+		content := make([]byte, stream.file.Size)
+		stream.file.ReadAt(content, 0)
+		for i := stream.unencrypted / 512 * 512; i < len(content); i += 512 {
+			block := content[i:]
+			if len(block) > 512 {
+				block = block[:512]
+			}
+			encrypted := testRC4(key(uint32(i/512)), block)
+			start := 0
+			if i < stream.unencrypted {
+				start = stream.unencrypted - i
+			}
+			copy(block[start:], encrypted[start:])
+		}
+		stream.file.WriteAt(content, 0)
+	}
+
+	if summary != nil {
+		// the stream keeps its size, as a stream shorter than the cutoff would be in the mini stream
+		encrypted := encryptedSummaryTestStream(key, "\x05SummaryInformation", summary)
+		if int64(len(encrypted)) > summaryInformation.Size {
+			t.Fatal("expected the encrypted summary stream to fit", len(encrypted))
+		}
+		summaryInformation.WriteAt(encrypted, 0)
+		renameTestStream(t, mem.b, "\x05SummaryInformation", "encryption")
+	}
+	return mem.b
+}
+
+// encrypt or decrypt b with a new RC4 cipher
+func testRC4(key, b []byte) []byte {
+	c, _ := rc4.NewCipher(key)
+	out := make([]byte, len(b))
+	c.XORKeyStream(out, b)
+	return out
+}
+
+func utf16TestBytes(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return b
+}
+
+// rename a stream by rewriting the name of its directory entry, which is 64 bytes of UTF-16 followed
+// by the size of the name in bytes ([MS-CFB] section 2.6.1)
+func renameTestStream(t *testing.T, b []byte, name, newName string) {
+	old := append(utf16TestBytes(name), 0, 0)
+	i := bytes.Index(b, append(append([]byte{}, old...), make([]byte, 64-len(old))...))
+	if i < 0 {
+		t.Fatal("expected a directory entry for", name)
+	}
+	entry := make([]byte, 66)
+	n := copy(entry, append(utf16TestBytes(newName), 0, 0))
+	entry[64] = byte(n)
+	copy(b[i:], entry)
+}
+
+// the RC4 EncryptionHeader ([MS-OFFCRYPTO] section 2.3.6.1) and the key for each block (section 2.3.6.2)
+func rc4TestHeader(password string) ([]byte, func(uint32) []byte) {
+	h0 := md5.Sum(utf16TestBytes(password))
+	h1 := md5.Sum(bytes.Repeat(append(h0[:5:5], testSalt...), 16))
+	key := func(block uint32) []byte {
+		h := md5.Sum(append(h1[:5:5], uint32Bytes(block)...))
+		return h[:]
+	}
+	hash := md5.Sum(testVerifier)
+	header := append([]byte{1, 0, 1, 0}, testSalt...)
+	return append(header, testRC4(key(0), append(testVerifier[:16:16], hash[:]...))...), key
+}
+
+// the RC4 CryptoAPI EncryptionHeader ([MS-OFFCRYPTO] section 2.3.5.1) and the key for each block
+// (section 2.3.5.2). Keys of 40 bits are padded with zeros to 128 bits
+func cryptoAPITestHeader(password string, keyBits int, flags uint32) ([]byte, func(uint32) []byte) {
+	h0 := sha1.Sum(append(testSalt[:16:16], utf16TestBytes(password)...))
+	key := func(block uint32) []byte {
+		h := sha1.Sum(append(h0[:], uint32Bytes(block)...))
+		if keyBits == 40 {
+			return append(h[:5], make([]byte, 11)...)
+		}
+		return h[:keyBits/8]
+	}
+
+	var encryptionHeader []byte
+	for _, v := range []uint32{flags, 0, 0x6801, 0x8004, uint32(keyBits), 1, 0, 0} { // RC4 and SHA-1
+		encryptionHeader = append(encryptionHeader, uint32Bytes(v)...)
+	}
+	encryptionHeader = append(encryptionHeader, utf16TestBytes("Microsoft Enhanced Cryptographic Provider v1.0\x00")...)
+	hash := sha1.Sum(testVerifier)
+	encrypted := testRC4(key(0), append(testVerifier[:16:16], hash[:]...))
+
+	header := append([]byte{4, 0, 2, 0}, uint32Bytes(flags)...)
+	header = append(append(header, uint32Bytes(uint32(len(encryptionHeader)))...), encryptionHeader...)
+	header = append(append(header, uint32Bytes(16)...), testSalt...)
+	return append(append(append(header, encrypted[:16]...), uint32Bytes(20)...), encrypted[16:]...), key
+}
+
+// a SummaryInformation property set stream ([MS-OLEPS]) with a code page and a title
+func summaryTestStream(title string) []byte {
+	value := append([]byte(title), 0)
+	value = append(value, make([]byte, (4-len(value)%4)%4)...)
+	codePage := []byte{0x02, 0, 0, 0, 0xE4, 0x04, 0, 0} // VT_I2 1252
+	titleProperty := append(append([]byte{0x1E, 0, 0, 0}, uint32Bytes(uint32(len(title)+1))...), value...)
+	const propertiesOffset = 8 + 2*8
+	propertySet := append(uint32Bytes(uint32(propertiesOffset+len(codePage)+len(titleProperty))), uint32Bytes(2)...)
+	for _, p := range [][2]uint32{{1, propertiesOffset}, {2, propertiesOffset + uint32(len(codePage))}} {
+		propertySet = append(append(propertySet, uint32Bytes(p[0])...), uint32Bytes(p[1])...)
+	}
+	propertySet = append(append(propertySet, codePage...), titleProperty...)
+
+	stream := append([]byte{0xFE, 0xFF, 0, 0, 0x06, 0, 0x02, 0}, make([]byte, 16)...)
+	fmtid := []byte{0xE0, 0x85, 0x9F, 0xF2, 0xF9, 0x4F, 0x68, 0x10, 0xAB, 0x91, 0x08, 0x00, 0x2B, 0x27, 0xB3, 0xD9}
+	stream = append(append(append(stream, uint32Bytes(1)...), fmtid...), uint32Bytes(48)...)
+	return append(stream, propertySet...)
+}
+
+// an encrypted summary stream holding one stream ([MS-OFFCRYPTO] section 2.3.5.4). The stream is
+// encrypted with the key of block 0, as are the offset and size of the descriptor array and the
+// array itself, each with a new cipher
+func encryptedSummaryTestStream(key func(uint32) []byte, name string, content []byte) []byte {
+	nameBytes := utf16TestBytes(name)
+	descriptor := append(append(uint32Bytes(8), uint32Bytes(uint32(len(content)))...), 0, 0, byte(len(nameBytes)/2), 0x01, 0, 0, 0, 0)
+	array := append(append(append(uint32Bytes(1), descriptor...), nameBytes...), 0, 0)
+	location := append(uint32Bytes(uint32(8+len(content))), uint32Bytes(uint32(len(array)))...)
+	stream := append(testRC4(key(0), location), testRC4(key(0), content)...)
+	return append(stream, testRC4(key(0), array)...)
+}
+
+// the encrypted copies of docFile.doc used by the tests
+func encryptedTestDoc(t *testing.T, name string) []byte {
+	const fCryptoAPI, fExternal = 0x04, 0x08
+	switch name {
+	case "rc4":
+		header, key := rc4TestHeader("sécret€")
+		return encryptTestDoc(t, header, key, nil)
+	case "rc4CryptoAPI": // fDocProps is not set, as the properties are encrypted
+		header, key := cryptoAPITestHeader("secret", 128, fCryptoAPI)
+		return encryptTestDoc(t, header, key, summaryTestStream("Encrypted Title"))
+	case "rc4CryptoAPI40":
+		header, key := cryptoAPITestHeader("secret", 40, fCryptoAPI|fExternal)
+		return encryptTestDoc(t, header, key, nil)
+	case "rc4CryptoAPI56":
+		header, key := cryptoAPITestHeader("secret", 56, fCryptoAPI|fExternal)
+		return encryptTestDoc(t, header, key, nil)
+	case "unknownEncryption": // the version of agile encryption, which .doc files do not use
+		header, key := cryptoAPITestHeader("secret", 56, fCryptoAPI|fExternal)
+		return encryptTestDoc(t, append([]byte{4, 0, 4, 0}, header[4:]...), key, nil)
+	}
+	t.Fatal("unknown test document", name)
+	return nil
+}
+
+// the EncryptionHeader at the start of the table stream of an encrypted test document
+func encryptionTestHeader(t *testing.T, doc []byte) []byte {
+	d, _ := mscfb.New(bytes.NewReader(doc))
+	wordDoc, table0, table1 := getWordDocAndTables(d)
+	fib, err := getFib(wordDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := readBlock(getActiveTable(wordDoc, table0, table1, fib), 0, fib.base.lKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header
+}
+
+// the keys for the first two blocks
+func TestEncryptionKeys(t *testing.T) {
+	tests := []struct {
+		doc, password string
+		keys          func([]byte, string) (blockKey, error)
+		block0        string
+		block1        string
+	}{
+		{"rc4", "sécret€", rc4Keys, "8c2870b057a226dea6c02afa76f7a58f", "190028be9499059102351549fdff318d"},
+		{"rc4CryptoAPI", "secret", cryptoAPIKeys, "58d62bf9da5291542be1c12ae44f77e2", "05adc3f5c6c0f8732af2dc32efbc7c29"},
+		{"rc4CryptoAPI40", "secret", cryptoAPIKeys, "58d62bf9da0000000000000000000000", ""},
+	}
+	for _, test := range tests {
+		header := encryptionTestHeader(t, encryptedTestDoc(t, test.doc))
+		key, err := test.keys(header, test.password)
+		if err != nil {
+			t.Error(test.doc, err)
+			continue
+		}
+		if k := hex.EncodeToString(key(0)); k != test.block0 {
+			t.Errorf("%s: expected key %s for block 0, got %s", test.doc, test.block0, k)
+		}
+		if k := hex.EncodeToString(key(1)); test.block1 != "" && k != test.block1 {
+			t.Errorf("%s: expected key %s for block 1, got %s", test.doc, test.block1, k)
+		}
+		if _, err := test.keys(header, "Secret"); err != ErrIncorrectPassword {
+			t.Error("expected incorrect password", test.doc, err)
+		}
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	r, _ := ParseDoc(f)
+	expected, _ := ioutil.ReadAll(r)
+
+	passwords := map[string]string{
+		"rc4":            "sécret€",
+		"rc4CryptoAPI":   "secret",
+		"rc4CryptoAPI40": "secret",
+		"rc4CryptoAPI56": "secret",
+	}
+	for name, password := range passwords {
+		doc := encryptedTestDoc(t, name)
+
+		_, err := ParseDoc(bytes.NewReader(doc))
+		if !errors.Is(err, ErrEncrypted) {
+			t.Error("expected encrypted error without a password", name, err)
+		}
+		_, err = ParseDoc(bytes.NewReader(doc), WithPassword("wrong", "also wrong"))
+		if !errors.Is(err, ErrIncorrectPassword) {
+			t.Error("expected incorrect password error", name, err)
+		}
+
+		r, err := ParseDoc(bytes.NewReader(doc), WithPassword("wrong"), WithPassword(password))
+		if err != nil {
+			t.Error("expected to decrypt", name, err)
+			continue
+		}
+		text, _ := ioutil.ReadAll(r)
+		if !bytes.Equal(text, expected) {
+			t.Error("expected decrypted text", name, string(text))
+		}
+	}
+}
+
+func TestDecryptDocument(t *testing.T) {
+	d, err := ParseDocument(bytes.NewReader(encryptedTestDoc(t, "rc4CryptoAPI")), WithPassword("secret"))
+	if err != nil {
+		t.Fatal("expected to decrypt", err)
+	}
+	if len(d.Stories) != 5 || len(d.Fields) != 11 {
+		t.Error("expected document", d.Stories, d.Fields)
+	}
+	// the SummaryInformation of the test document is only in the encrypted summary stream
+	if d.Metadata.Title != "Encrypted Title" || d.Metadata.Author != "" {
+		t.Error("expected the properties from the encrypted summary stream", d.Metadata)
+	}
+
+	if m, err := ParseMetadata(bytes.NewReader(encryptedTestDoc(t, "rc4CryptoAPI40")), WithPassword("secret")); err != nil || m.Title != "Work Experience" {
+		t.Error("expected the properties of a document that are not encrypted", m, err)
+	}
+}
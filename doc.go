@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"unicode/utf16"
 
 	"github.com/mattetti/filebuffer"
 	"github.com/richardlehane/mscfb"
@@ -24,44 +26,150 @@ type allReader interface {
 }
 
 func wrapError(e error) error {
-	return errors.New("Error processing file: " + e.Error())
+	return fmt.Errorf("Error processing file: %w", e)
+}
+
+// wordFile holds the streams and top level structures of a Word Binary File
+type wordFile struct {
+	cfb     *mscfb.Reader
+	path    []string // the storage of the document in the compound file, which is empty for the root
+	wordDoc *mscfb.File
+	table   *mscfb.File
+	data    *mscfb.File
+	fib     *fib
+	clx     *clx
+	options *options          // the options the document was opened with
+	summary map[string][]byte // property set streams decrypted from the encrypted summary stream, by name
 }
 
 // ParseDoc converts a standard io.Reader from a Microsoft Word
 // .doc binary file and returns a reader (actually a bytes.Buffer)
 // which will output the plain text found in the .doc file
-func ParseDoc(r io.Reader) (io.Reader, error) {
-	ra, ok := r.(io.ReaderAt)
-	if !ok {
-		ra, _, err := toMemoryBuffer(r)
+func ParseDoc(r io.Reader, opts ...Option) (io.Reader, error) {
+	ra, err := toReaderAt(r)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	o := getOptions(opts)
+	w, err := openWordFile(ra, o)
+	var legacy *LegacyFormatError
+	if errors.As(err, &legacy) { // only the text of files from before Word 6.0 can be read
+		text, err := legacy.file.text(ra, o)
 		if err != nil {
 			return nil, wrapError(err)
 		}
-		defer ra.Close()
+		return text, nil
 	}
-
-	d, err := mscfb.New(ra)
 	if err != nil {
 		return nil, wrapError(err)
 	}
+	return getText(w, o)
+}
+
+func toReaderAt(r io.Reader) (io.ReaderAt, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return ra, nil
+	}
+	ra, _, err := toMemoryBuffer(r)
+	return ra, err
+}
+
+// open the compound file and read the FIB and piece table which are needed for everything else
+func openWordFile(ra io.ReaderAt, o *options) (*wordFile, error) {
+	legacy, err := sniffFormat(ra)
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil {
+		return nil, &LegacyFormatError{Format: legacy.format, file: legacy}
+	}
+
+	d, err := newCompoundFile(ra)
+	if err != nil {
+		return nil, err
+	}
 
 	wordDoc, table0, table1 := getWordDocAndTables(d)
 	fib, err := getFib(wordDoc)
 	if err != nil {
-		return nil, wrapError(err)
+		return nil, err
 	}
 
-	table := getActiveTable(table0, table1, fib)
+	table := getActiveTable(wordDoc, table0, table1, fib)
+	if table == nil {
+		return nil, errTable
+	}
+	var summary map[string][]byte
+	if fib.base.fEncrypted {
+		if d, summary, err = decrypt(ra, fib, wordDoc, table, o.passwords); err != nil {
+			return nil, err
+		}
+		wordDoc, table0, table1 = getWordDocAndTables(d)
+		if fib, err = getFib(wordDoc); err != nil {
+			return nil, err
+		}
+		if table = getActiveTable(wordDoc, table0, table1, fib); table == nil {
+			return nil, errTable
+		}
+	}
+
+	w, err := newWordFile(d, nil, wordDoc, table, fib, o)
+	if err != nil {
+		return nil, err
+	}
+	w.summary = summary
+	return w, nil
+}
+
+// open a Word document that is embedded in the storage at path in the compound file of another
+// document (section 2.1.4). Embedded documents that are encrypted cannot be read
+func openEmbeddedWordFile(d *mscfb.Reader, path []string, o *options) (*wordFile, error) {
+	wordDoc, table0, table1 := getWordDocAndTables(d, path...)
+	fib, err := getFib(wordDoc)
+	if err != nil {
+		return nil, err
+	}
+	if fib.base.fEncrypted {
+		return nil, ErrEncrypted
+	}
+	table := getActiveTable(wordDoc, table0, table1, fib)
 	if table == nil {
-		return nil, wrapError(errTable)
+		return nil, errTable
 	}
+	return newWordFile(d, path, wordDoc, table, fib, o)
+}
 
+// read the piece table of the document in the storage at path, once the streams have been found
+func newWordFile(d *mscfb.Reader, path []string, wordDoc, table *mscfb.File, fib *fib, o *options) (*wordFile, error) {
 	clx, err := getClx(table, fib)
 	if err != nil {
-		return nil, wrapError(err)
+		return nil, err
 	}
+	return &wordFile{cfb: d, path: path, wordDoc: wordDoc, table: table, data: getStream(d, "Data", path...), fib: fib,
+		clx: clx, options: o}, nil
+}
 
-	return getText(wordDoc, clx)
+// read the directory of a compound file. mscfb can give files in storages that are nested more
+// than two deep the paths of other files, so the paths are rebuilt from the depth of each file
+// and the storages before it, as the files in a storage follow it
+func newCompoundFile(ra io.ReaderAt) (*mscfb.Reader, error) {
+	d, err := mscfb.New(ra)
+	if err != nil {
+		return nil, err
+	}
+	var storages []string
+	for _, f := range d.File {
+		depth := len(f.Path)
+		if depth > len(storages) { // not possible with the traversal mscfb uses
+			depth = len(storages)
+		}
+		f.Path = append([]string{}, storages[:depth]...)
+		storages = storages[:depth]
+		if f.FileInfo().IsDir() {
+			storages = append(storages, f.Name)
+		}
+	}
+	return d, nil
 }
 
 func toMemoryBuffer(r io.Reader) (allReader, int64, error) {
@@ -74,132 +182,154 @@ func toMemoryBuffer(r io.Reader) (allReader, int64, error) {
 	return fb, size, nil
 }
 
-func getText(wordDoc *mscfb.File, clx *clx) (io.Reader, error) {
+func getText(w *wordFile, o *options) (io.Reader, error) {
+	chars, err := getCharacters(w.wordDoc, w.clx)
+	if err != nil {
+		return nil, err
+	}
+	replaced := getSymbols(w, chars)
+	for cp, text := range getWordArt(w) {
+		replaced[cp] = text
+	}
+	for cp, text := range getEquations(w, chars) {
+		replaced[cp] = text
+	}
 	var buf bytes.Buffer
-	for i := 0; i < len(clx.pcdt.PlcPcd.aPcd); i++ {
-		pcd := clx.pcdt.PlcPcd.aPcd[i]
-		cp := clx.pcdt.PlcPcd.aCP[i]
-		cpNext := clx.pcdt.PlcPcd.aCP[i+1]
+	translateText(chars, replaced, o.special, &buf)
+	return bytes.NewBufferString(o.text.render(buf.String())), nil
+}
 
-		var start, end, size int
+// read every character in the document, one UTF-16 code unit per CP (section 2.4.1)
+func getCharacters(wordDoc *mscfb.File, clx *clx) ([]uint16, error) {
+	aCP := clx.pcdt.PlcPcd.aCP
+	chars := make([]uint16, 0, aCP[len(aCP)-1])
+	for i, pcd := range clx.pcdt.PlcPcd.aPcd {
+		count := aCP[i+1] - aCP[i]
+		if count <= 0 {
+			continue
+		}
 		if pcd.fc.fCompressed {
-			size = 1
-			start = pcd.fc.fc / 2
-			end = start + cpNext - cp
+			b := make([]byte, count)
+			if _, err := wordDoc.ReadAt(b, int64(pcd.fc.fc/2)); err != nil {
+				return nil, err
+			}
+			for _, c := range b {
+				chars = append(chars, ansiToUnicode(c))
+			}
 		} else {
-			size = 2
-			start = pcd.fc.fc
-			end = start + 2*(cpNext-cp)
+			b := make([]byte, count*2)
+			if _, err := wordDoc.ReadAt(b, int64(pcd.fc.fc)); err != nil {
+				return nil, err
+			}
+			for j := 0; j < count; j++ {
+				chars = append(chars, binary.LittleEndian.Uint16(b[j*2:]))
+			}
 		}
-
-		b := make([]byte, end-start)
-		_, err := wordDoc.ReadAt(b, int64(start/size)) // read all the characters
-		if err != nil {
-			return nil, err
-		}
-		translateText(b, &buf, pcd.fc.fCompressed)
 	}
-	return &buf, nil
+	return chars, nil
 }
 
-func translateText(b []byte, buf *bytes.Buffer, fCompressed bool) {
-	fieldLevel := 0
+// write the text of the characters as UTF-8, leaving out field codes and control characters, except
+// for EQ fields, whose equations are written as LaTeX. replaced holds the text written in place of
+// characters such as symbols, WordArt and equation anchors, by index, and special the text to
+// write for each special character
+func translateText(chars []uint16, replaced map[int][]uint16, special map[SpecialCharacter]string, buf *bytes.Buffer) {
+	var text, instruction []uint16
 	var isFieldChar bool
-	for cIndex := range b {
+	for i, c := range chars {
 		// Handle special field characters (section 2.8.25)
-		if b[cIndex] == 0x13 {
+		if c == 0x13 {
 			isFieldChar = true
-			fieldLevel++
-			continue
-		} else if b[cIndex] == 0x14 {
-			isFieldChar = false
+			instruction = instruction[:0]
 			continue
-		} else if b[cIndex] == 0x15 {
+		} else if c == 0x14 || c == 0x15 {
+			if c == 0x15 && isFieldChar { // a field without a result
+				text = append(text, fieldInstructionText(instruction)...)
+			}
 			isFieldChar = false
 			continue
 		} else if isFieldChar {
+			instruction = append(instruction, c)
 			continue
 		}
 
-		if b[cIndex] == 7 { // table column separator
-			buf.WriteByte(' ')
-			continue
-		} else if b[cIndex] < 32 && b[cIndex] != 9 && b[cIndex] != 10 && b[cIndex] != 13 { // skip non-printable ASCII characters
-			//buf.Write([]byte(fmt.Sprintf("|%#x|", b[cIndex])))
-			continue
-		}
-
-		if fCompressed { // compressed, so replace compressed characters
-			buf.Write(replaceCompressed(b[cIndex]))
-		} else {
-			buf.Write(b)
+		if r, ok := replaced[i]; ok {
+			text = append(text, r...)
+		} else if s, ok := special[SpecialCharacter(c)]; ok {
+			text = append(text, utf16.Encode([]rune(s))...)
+		} else if c == 7 { // table column separator
+			text = append(text, ' ')
+		} else if c >= 32 || c == 9 || c == 10 || c == 13 { // skip non-printable ASCII characters
+			text = append(text, c)
 		}
 	}
+	buf.WriteString(string(utf16.Decode(text)))
 }
 
-func replaceCompressed(char byte) []byte {
-	var v uint16
+// map compressed characters to Unicode. Most are the same, but some are not (section 2.9.73)
+func ansiToUnicode(char byte) uint16 {
 	switch char {
 	case 0x82:
-		v = 0x201A
+		return 0x201A
 	case 0x83:
-		v = 0x0192
+		return 0x0192
 	case 0x84:
-		v = 0x201E
+		return 0x201E
 	case 0x85:
-		v = 0x2026
+		return 0x2026
 	case 0x86:
-		v = 0x2020
+		return 0x2020
 	case 0x87:
-		v = 0x2021
+		return 0x2021
 	case 0x88:
-		v = 0x02C6
+		return 0x02C6
 	case 0x89:
-		v = 0x2030
+		return 0x2030
 	case 0x8A:
-		v = 0x0160
+		return 0x0160
 	case 0x8B:
-		v = 0x2039
+		return 0x2039
 	case 0x8C:
-		v = 0x0152
+		return 0x0152
 	case 0x91:
-		v = 0x2018
+		return 0x2018
 	case 0x92:
-		v = 0x2019
+		return 0x2019
 	case 0x93:
-		v = 0x201C
+		return 0x201C
 	case 0x94:
-		v = 0x201D
+		return 0x201D
 	case 0x95:
-		v = 0x2022
+		return 0x2022
 	case 0x96:
-		v = 0x2013
+		return 0x2013
 	case 0x97:
-		v = 0x2014
+		return 0x2014
 	case 0x98:
-		v = 0x02DC
+		return 0x02DC
 	case 0x99:
-		v = 0x2122
+		return 0x2122
 	case 0x9A:
-		v = 0x0161
+		return 0x0161
 	case 0x9B:
-		v = 0x203A
+		return 0x203A
 	case 0x9C:
-		v = 0x0153
+		return 0x0153
 	case 0x9F:
-		v = 0x0178
-	default:
-		return []byte{char}
+		return 0x0178
 	}
-	out := make([]byte, 2)
-	binary.LittleEndian.PutUint16(out, v)
-	return out
+	return uint16(char)
 }
 
-func getWordDocAndTables(r *mscfb.Reader) (*mscfb.File, *mscfb.File, *mscfb.File) {
+// find the WordDocument stream and the table streams in the storage at path, which is the root
+// if it is empty
+func getWordDocAndTables(r *mscfb.Reader, path ...string) (*mscfb.File, *mscfb.File, *mscfb.File) {
 	var wordDoc, table0, table1 *mscfb.File
 	for i := 0; i < len(r.File); i++ {
 		stream := r.File[i]
+		if !isPath(stream.Path, path...) {
+			continue
+		}
 
 		switch stream.Name {
 		case "WordDocument":
@@ -213,7 +343,22 @@ func getWordDocAndTables(r *mscfb.Reader) (*mscfb.File, *mscfb.File, *mscfb.File
 	return wordDoc, table0, table1
 }
 
-func getActiveTable(table0 *mscfb.File, table1 *mscfb.File, f *fib) *mscfb.File {
+// find a stream in the storage at path, which is the root of the compound file if it is empty
+func getStream(r *mscfb.Reader, name string, path ...string) *mscfb.File {
+	for _, stream := range r.File {
+		if stream.Name == name && isPath(stream.Path, path...) {
+			return stream
+		}
+	}
+	return nil
+}
+
+// the stream that holds the structures the FIB points to, which is the WordDocument stream in
+// Word 6.0 and Word 95 files and otherwise the table stream given by fWhichTblStm
+func getActiveTable(wordDoc, table0, table1 *mscfb.File, f *fib) *mscfb.File {
+	if f.isWord6() {
+		return wordDoc
+	}
 	if f.base.fWhichTblStm == 0 {
 		return table0
 	}
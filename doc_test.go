@@ -5,6 +5,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
 )
 
 func TestParseSimpleDoc(t *testing.T) {
@@ -105,3 +108,35 @@ Some info from inside a text box
 
 
 `
+
+func TestTranslateText(t *testing.T) {
+	chars := utf16.Encode([]rune("a\x13 PAGE \x141\x15b\x07c\x01d(e é“\U0001F600\r"))
+	var buf bytes.Buffer
+	translateText(chars, map[int][]uint16{16: {0x03B1}}, nil, &buf)
+	if s := buf.String(); s != "a1b cdαe é“\U0001F600\r" {
+		t.Errorf("expected UTF-8 text without field codes, got %q", s)
+	}
+}
+
+// a structure whose lcb runs past the end of its stream is rejected before it is read into memory
+func TestReadBlockOutOfRange(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	defer f.Close()
+	doc, _ := mscfb.New(f)
+	wordDoc, table0, table1 := getWordDocAndTables(doc)
+	fib, _ := getFib(wordDoc)
+	table := getActiveTable(wordDoc, table0, table1, fib)
+	for _, test := range []struct{ fc, lcb int }{{-1, 4}, {0, int(table.Size) + 1}, {int(table.Size), 1}, {4, 0x7FFFFFF0}} {
+		if _, err := readBlock(table, test.fc, test.lcb); err != errBlockOutOfRange {
+			t.Error("expected a block outside the stream to fail", test, err)
+		}
+	}
+	if b, err := readBlock(table, int(table.Size)-4, 4); err != nil || len(b) != 4 {
+		t.Error("expected the last bytes of the stream", b, err)
+	}
+
+	fib.fibRgFcLcb.lcbStshf = 0xD6000000
+	if _, err := getStsh(table, fib); err != errBlockOutOfRange {
+		t.Error("expected a style sheet larger than the table stream to fail", err)
+	}
+}
@@ -0,0 +1,452 @@
+package doc2txt
+
+import (
+	"io"
+	"time"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// StoryType identifies a document part (section 2.3)
+type StoryType string
+
+// The document parts, in the order their text is stored
+const (
+	StoryMain            StoryType = "main"
+	StoryFootnotes       StoryType = "footnotes"
+	StoryHeaders         StoryType = "headers"
+	StoryComments        StoryType = "comments"
+	StoryEndnotes        StoryType = "endnotes"
+	StoryTextboxes       StoryType = "textboxes"
+	StoryHeaderTextboxes StoryType = "headerTextboxes"
+)
+
+// Document is the parsed content of a Word document. All CP values are character
+// positions in the document as a whole, so they can be compared across stories
+type Document struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Metadata      Metadata         `json:"metadata"`
+	Fonts         []Font           `json:"fonts,omitempty"`
+	Styles        []Style          `json:"styles,omitempty"`
+	Lists         []List           `json:"lists,omitempty"`
+	ListInstances []ListInstance   `json:"listInstances,omitempty"`
+	Stories       []Story          `json:"stories"`
+	Fields        []Field          `json:"fields,omitempty"`
+	Comments      []Comment        `json:"comments,omitempty"`
+	Footnotes     []Note           `json:"footnotes,omitempty"`
+	Endnotes      []Note           `json:"endnotes,omitempty"`
+	Revisions     []Revision       `json:"revisions,omitempty"`
+	Pictures      []Picture        `json:"pictures,omitempty"`
+	Shapes        []Shape          `json:"shapes,omitempty"`
+	Objects       []EmbeddedObject `json:"objects,omitempty"`
+}
+
+// Metadata describes the file the document was read from. FibVersion is the nFib of
+// the FibBase, and FibVersionNew the nFib of the FibRgCswNew that files from Word 2000
+// on have. Created is the creation time from the summary information if it is there,
+// and Modified is the time the compound file was last modified. The other fields come
+// from the summary information
+type Metadata struct {
+	FibVersion     int        `json:"fibVersion"`
+	FibVersionNew  int        `json:"fibVersionNew,omitempty"`
+	Language       int        `json:"language"`
+	Title          string     `json:"title,omitempty"`
+	Subject        string     `json:"subject,omitempty"`
+	Author         string     `json:"author,omitempty"`
+	Keywords       string     `json:"keywords,omitempty"`
+	Comments       string     `json:"comments,omitempty"`
+	LastSavedBy    string     `json:"lastSavedBy,omitempty"`
+	RevisionNumber string     `json:"revisionNumber,omitempty"`
+	Company        string     `json:"company,omitempty"`
+	Manager        string     `json:"manager,omitempty"`
+	Created        *time.Time `json:"created,omitempty"`
+	Modified       *time.Time `json:"modified,omitempty"`
+	Saved          *time.Time `json:"saved,omitempty"`
+	Printed        *time.Time `json:"printed,omitempty"`
+	PageCount      int        `json:"pageCount,omitempty"`
+	WordCount      int        `json:"wordCount,omitempty"`
+	CharCount      int        `json:"charCount,omitempty"`
+
+	CustomProperties []CustomProperty `json:"customProperties,omitempty"`
+	Settings         *Settings        `json:"settings,omitempty"`
+}
+
+// Document protection types
+const (
+	ProtectionNone         = "none"
+	ProtectionReadOnly     = "readOnly"
+	ProtectionComments     = "comments"
+	ProtectionTrackChanges = "trackChanges"
+	ProtectionForms        = "forms"
+)
+
+// Settings are the document settings and statistics that Word stores with the document.
+// Tab widths are in twips. The counts are the ones Word last calculated and may be estimates
+type Settings struct {
+	Protection          string     `json:"protection"`
+	TrackChanges        bool       `json:"trackChanges,omitempty"`
+	DefaultTabWidth     int        `json:"defaultTabWidth,omitempty"`
+	FootnoteNumbering   string     `json:"footnoteNumbering,omitempty"`
+	EndnoteNumbering    string     `json:"endnoteNumbering,omitempty"`
+	Created             *time.Time `json:"created,omitempty"`
+	Revised             *time.Time `json:"revised,omitempty"`
+	Printed             *time.Time `json:"printed,omitempty"`
+	Revisions           int        `json:"revisions,omitempty"`
+	EditingMinutes      int        `json:"editingMinutes,omitempty"`
+	Words               int        `json:"words,omitempty"`
+	Characters          int        `json:"characters,omitempty"`
+	CharactersAndSpaces int        `json:"charactersAndSpaces,omitempty"`
+	Pages               int        `json:"pages,omitempty"`
+	Paragraphs          int        `json:"paragraphs,omitempty"`
+	Lines               int        `json:"lines,omitempty"`
+}
+
+// Custom property types
+const (
+	PropertyString = "string"
+	PropertyInt    = "int"
+	PropertyBool   = "bool"
+	PropertyTime   = "time"
+	PropertyFloat  = "float"
+)
+
+// CustomProperty is a user defined document property. Value is a string, int64,
+// bool, time.Time or float64 as given by Type
+type CustomProperty struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Font is a font from the font table. Runs refer to fonts by name. Charset is the
+// Windows character set, Family is roman, swiss, modern, script or decorative and
+// Pitch is fixed or variable. Family and Pitch are empty when they are not known
+type Font struct {
+	Name     string `json:"name"`
+	AltName  string `json:"altName,omitempty"`
+	Charset  int    `json:"charset"`
+	Family   string `json:"family,omitempty"`
+	Pitch    string `json:"pitch,omitempty"`
+	TrueType bool   `json:"trueType,omitempty"`
+}
+
+// Picture formats
+const (
+	PictureEMF  = "emf"
+	PictureWMF  = "wmf"
+	PicturePICT = "pict"
+	PictureJPEG = "jpeg"
+	PicturePNG  = "png"
+	PictureDIB  = "dib"
+	PictureTIFF = "tiff"
+)
+
+// Picture is an image anchored at CP. Width and Height are the displayed size in twips,
+// and PixelWidth and PixelHeight the size of PNG, JPEG and DIB images. Data is the image
+// file, which is left out of the JSON. Metafiles are decompressed, and DIB data is a
+// bitmap without the file header
+type Picture struct {
+	CP          int    `json:"cp"`
+	Format      string `json:"format"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	PixelWidth  int    `json:"pixelWidth,omitempty"`
+	PixelHeight int    `json:"pixelHeight,omitempty"`
+	ShapeID     int    `json:"shapeId,omitempty"` // the ID of the floating shape of the picture
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"` // the alternative text
+	Data        []byte `json:"-"`
+}
+
+// the origins that the positions of floating shapes are relative to
+const (
+	ShapeOriginMargin    = "margin"
+	ShapeOriginPage      = "page"
+	ShapeOriginColumn    = "column"
+	ShapeOriginParagraph = "paragraph"
+)
+
+// the ways text wraps around floating shapes. With ShapeWrapNone the shape is in front of the
+// text, or behind it if BehindText is set
+const (
+	ShapeWrapAround    = "around"
+	ShapeWrapTopBottom = "topBottom"
+	ShapeWrapSquare    = "square"
+	ShapeWrapNone      = "none"
+	ShapeWrapTight     = "tight"
+	ShapeWrapThrough   = "through"
+)
+
+// Shape is a floating shape, such as a picture or a text box, anchored at the character at CP.
+// ID is the shape ID, which is also the ShapeID of the picture of the shape in Pictures. Type
+// is the MSOSPT shape type, such as 75 for a picture frame or 202 for a text box. The position
+// and size are in twips, with Left and Top relative to the origins
+type Shape struct {
+	CP               int    `json:"cp"`
+	ID               int    `json:"id"`
+	Type             int    `json:"type"`
+	Left             int    `json:"left"`
+	Top              int    `json:"top"`
+	Width            int    `json:"width"`
+	Height           int    `json:"height"`
+	HorizontalOrigin string `json:"horizontalOrigin,omitempty"`
+	VerticalOrigin   string `json:"verticalOrigin,omitempty"`
+	Wrap             string `json:"wrap,omitempty"`
+	BehindText       bool   `json:"behindText,omitempty"`
+	Name             string `json:"name,omitempty"`
+	Description      string `json:"description,omitempty"` // the alternative text
+	Text             string `json:"text,omitempty"`        // the text of WordArt
+}
+
+// EmbeddedObject is an OLE object embedded in the document, such as a spreadsheet, another
+// document, an equation or a package. Name is the name of its storage in the ObjectPool, and CP
+// the character that anchors it, or -1 if no character does. The native data of the object is
+// read from NativeStream, which is one of Streams, using Native. Embedded Word documents have
+// their text, as ParseDoc would write it, and the objects embedded in them, and equations their
+// LaTeX
+type EmbeddedObject struct {
+	Name         string           `json:"name"`
+	CP           int              `json:"cp"`
+	ClassID      string           `json:"classId,omitempty"`
+	ProgID       string           `json:"progId,omitempty"`
+	UserType     string           `json:"userType,omitempty"`
+	Streams      []string         `json:"streams,omitempty"`
+	NativeStream string           `json:"nativeStream,omitempty"`
+	Text         string           `json:"text,omitempty"`    // the text of an embedded Word document
+	Objects      []EmbeddedObject `json:"objects,omitempty"` // the objects embedded in an embedded Word document
+	Package      *Package         `json:"package,omitempty"`
+	Equation     string           `json:"equation,omitempty"` // the LaTeX of an Equation Editor or MathType equation
+	native       *mscfb.File
+}
+
+// Package is a file embedded with Packager, such as a PDF or a zip file dragged into the
+// document. FileName is the name it is shown with and SourcePath where it was embedded from.
+// Packages that link to the file rather than holding it have no data
+type Package struct {
+	FileName   string `json:"fileName"`
+	SourcePath string `json:"sourcePath,omitempty"`
+	Size       int    `json:"size"`
+	Data       []byte `json:"-"`
+}
+
+// Style is a style definition from the style sheet
+type Style struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	BasedOn string `json:"basedOn,omitempty"`
+}
+
+// List is a list definition, with one level for a simple list and nine otherwise. ID is the
+// list's identifier, which is unique in the document
+type List struct {
+	ID     int         `json:"id"`
+	Levels []ListLevel `json:"levels"`
+}
+
+// ListLevel is the numbering of one level of a list. Format is the number format, such as
+// "decimal", "lowerRoman" or "bullet". Text is the text of the number, with %1 to %9 standing
+// for the numbers of the levels as in DOCX, such as "%1.%2.". Follow is "tab", "space" or
+// "nothing", for what comes between the number and the text. The indents are in twips, with a
+// negative FirstLine for a hanging indent
+type ListLevel struct {
+	Start     int    `json:"start"`
+	Format    string `json:"format"`
+	Text      string `json:"text"`
+	Alignment string `json:"alignment,omitempty"`
+	Follow    string `json:"follow"`
+	Indent    int    `json:"indent,omitempty"`
+	FirstLine int    `json:"firstLine,omitempty"`
+}
+
+// ListInstance is a use of a List by paragraphs, which numbers them in sequence. ID is the
+// number that paragraphs refer to it by, and ListID the ID of the List. Overrides change the
+// numbering of some levels for the paragraphs of this instance
+type ListInstance struct {
+	ID        int            `json:"id"`
+	ListID    int            `json:"listId"`
+	Overrides []ListOverride `json:"overrides,omitempty"`
+}
+
+// ListOverride restarts the numbering of a level at Start, and replaces the level with Definition
+// if it is set
+type ListOverride struct {
+	Level      int        `json:"level"`
+	Start      int        `json:"start"`
+	Definition *ListLevel `json:"definition,omitempty"`
+}
+
+// ListItem places a paragraph at a level, from 0 to 8, of a list instance
+type ListItem struct {
+	Instance int `json:"instance"`
+	Level    int `json:"level"`
+}
+
+// Story is one document part. Only the main story is divided into more than one section
+type Story struct {
+	Type     StoryType `json:"type"`
+	CP       int       `json:"cp"`
+	Length   int       `json:"length"`
+	Sections []Section `json:"sections"`
+}
+
+// Section is a range of a story that shares page layout
+type Section struct {
+	CP     int     `json:"cp"`
+	Length int     `json:"length"`
+	Blocks []Block `json:"blocks"`
+}
+
+// Block is either a paragraph or a table, as given by Type
+type Block struct {
+	Type      string     `json:"type"`
+	Paragraph *Paragraph `json:"paragraph,omitempty"`
+	Table     *Table     `json:"table,omitempty"`
+}
+
+// Block types
+const (
+	BlockParagraph = "paragraph"
+	BlockTable     = "table"
+)
+
+// Paragraph is a paragraph of text. Text holds the visible text of all the runs
+// that are not deleted revisions
+type Paragraph struct {
+	CP        int       `json:"cp"`
+	Style     string    `json:"style,omitempty"`
+	Alignment string    `json:"alignment,omitempty"`
+	List      *ListItem `json:"list,omitempty"`
+	Text      string    `json:"text"`
+	Runs      []Run     `json:"runs,omitempty"`
+}
+
+// Run is a range of text within a paragraph that shares character formatting
+type Run struct {
+	CP            int     `json:"cp"`
+	Text          string  `json:"text"`
+	Bold          bool    `json:"bold,omitempty"`
+	Italic        bool    `json:"italic,omitempty"`
+	Underline     bool    `json:"underline,omitempty"`
+	Strike        bool    `json:"strike,omitempty"`
+	SmallCaps     bool    `json:"smallCaps,omitempty"`
+	Caps          bool    `json:"caps,omitempty"`
+	Hidden        bool    `json:"hidden,omitempty"`
+	Font          string  `json:"font,omitempty"`
+	Size          float64 `json:"size,omitempty"` // in points
+	Color         string  `json:"color,omitempty"`
+	VerticalAlign string  `json:"verticalAlign,omitempty"`
+	Inserted      bool    `json:"inserted,omitempty"`
+	Deleted       bool    `json:"deleted,omitempty"`
+}
+
+// Table is a table of rows of cells
+type Table struct {
+	Rows []TableRow `json:"rows"`
+}
+
+// TableRow is one row of a table
+type TableRow struct {
+	Cells []TableCell `json:"cells"`
+}
+
+// TableCell is one cell of a table row
+type TableCell struct {
+	Paragraphs []Paragraph `json:"paragraphs"`
+}
+
+// Field is a field such as a hyperlink, page number or table of contents. EQ fields have the
+// equation they display as LaTeX
+type Field struct {
+	Story       StoryType `json:"story"`
+	CP          int       `json:"cp"`
+	Type        string    `json:"type"`
+	Instruction string    `json:"instruction"`
+	Result      string    `json:"result,omitempty"`
+	Equation    string    `json:"equation,omitempty"` // the LaTeX of an EQ field
+}
+
+// Comment is an annotation. CP is the location of the comment reference in the main story
+type Comment struct {
+	CP       int    `json:"cp"`
+	Author   string `json:"author,omitempty"`
+	Initials string `json:"initials,omitempty"`
+	Text     string `json:"text"`
+}
+
+// Note is a footnote or endnote. CP is the location of the note reference in the main story
+type Note struct {
+	CP   int    `json:"cp"`
+	Text string `json:"text"`
+}
+
+// Revision types
+const (
+	RevisionInsertion = "insertion"
+	RevisionDeletion  = "deletion"
+)
+
+// Revision is a range of text that was inserted or deleted while revision marking was on
+type Revision struct {
+	Type   string     `json:"type"`
+	Story  StoryType  `json:"story"`
+	CP     int        `json:"cp"`
+	Length int        `json:"length"`
+	Author string     `json:"author,omitempty"`
+	Date   *time.Time `json:"date,omitempty"`
+	Text   string     `json:"text"`
+}
+
+// ParseMetadata reads only the metadata of a Microsoft Word .doc binary file
+func ParseMetadata(r io.Reader, opts ...Option) (*Metadata, error) {
+	ra, err := toReaderAt(r)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	w, err := openWordFile(ra, getOptions(opts))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	m := getMetadata(w)
+	return &m, nil
+}
+
+// ParseEmbeddedObjects lists the OLE objects embedded in a Microsoft Word .doc binary file. The
+// native data of the objects can be read until r is closed
+func ParseEmbeddedObjects(r io.Reader, opts ...Option) ([]EmbeddedObject, error) {
+	ra, err := toReaderAt(r)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	w, err := openWordFile(ra, getOptions(opts))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	chars, err := getCharacters(w.wordDoc, w.clx)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	chpx, err := getChpxRuns(w.wordDoc, w.table, w.fib)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return getEmbeddedObjects(w, chars, getCPRuns(w.clx, chpx), 0), nil
+}
+
+// ParseDocument reads a Microsoft Word .doc binary file and returns its
+// stories, sections, paragraphs, tables, fields, comments and revisions
+func ParseDocument(r io.Reader, opts ...Option) (*Document, error) {
+	ra, err := toReaderAt(r)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	w, err := openWordFile(ra, getOptions(opts))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	d, err := getDocument(w)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return d, nil
+}
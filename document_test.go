@@ -0,0 +1,117 @@
+package doc2txt
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestParseDocumentSimple(t *testing.T) {
+	f, _ := os.Open(`testData/simpleDoc.doc`)
+	d, err := ParseDocument(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+	if d.SchemaVersion != JSONSchemaVersion || d.Metadata.FibVersion != 0x00C1 || d.Metadata.FibVersionNew != 0x0112 || d.Metadata.Language != 1033 || d.Metadata.Created == nil || d.Metadata.Modified == nil {
+		t.Error("expected valid metadata", d.SchemaVersion, d.Metadata)
+	}
+	if len(d.Stories) != 1 || d.Stories[0].Type != StoryMain || d.Stories[0].Length != 6 || len(d.Stories[0].Sections) != 1 {
+		t.Fatal("expected single main story", d.Stories)
+	}
+	blocks := d.Stories[0].Sections[0].Blocks
+	if len(blocks) != 1 || blocks[0].Type != BlockParagraph || blocks[0].Paragraph.Text != "12345" || blocks[0].Paragraph.Style != "Normal" ||
+		len(blocks[0].Paragraph.Runs) != 1 || blocks[0].Paragraph.Runs[0].Size != 11 {
+		t.Error("expected one paragraph", blocks)
+	}
+}
+
+func TestParseDocumentComplicated(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	d, err := ParseDocument(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+
+	types := []StoryType{StoryMain, StoryFootnotes, StoryHeaders, StoryEndnotes, StoryTextboxes}
+	if len(d.Stories) != len(types) {
+		t.Fatal("expected stories", d.Stories)
+	}
+	for i, typ := range types {
+		if d.Stories[i].Type != typ {
+			t.Error("expected story type", typ, d.Stories[i].Type)
+		}
+	}
+
+	blocks := d.Stories[0].Sections[0].Blocks
+	title := blocks[0].Paragraph
+	if title.Text != "Name Here in Big" || title.Style != "Normal (Web)" || title.Alignment != "center" || len(title.Runs) != 1 ||
+		!title.Runs[0].Bold || title.Runs[0].Size != 30 || title.Runs[0].Color != "000080" {
+		t.Error("expected formatted title", title)
+	}
+	var underlined, italic *Paragraph
+	var table *Table
+	for _, block := range blocks {
+		switch {
+		case block.Table != nil:
+			table = block.Table
+		case block.Paragraph.Text == "Underlined":
+			underlined = block.Paragraph
+		case block.Paragraph.Text == "Italics":
+			italic = block.Paragraph
+		}
+	}
+	if underlined == nil || !underlined.Runs[0].Underline || italic == nil || !italic.Runs[0].Italic {
+		t.Error("expected underlined and italic text", underlined, italic)
+	}
+	if table == nil || len(table.Rows) != 3 || len(table.Rows[2].Cells) != 2 ||
+		table.Rows[2].Cells[0].Paragraphs[0].Text != "Hopefully, we" || table.Rows[2].Cells[1].Paragraphs[0].Text != "get it" {
+		t.Error("expected 3x2 table", table)
+	}
+
+	if len(d.Fields) != 11 || d.Fields[0].Type != "HYPERLINK" || d.Fields[0].Instruction != `HYPERLINK  "https://google.com"` ||
+		d.Fields[0].Result != "Link to something" || d.Fields[1].Type != "TOC" || d.Fields[10].Story != StoryHeaders || d.Fields[10].Result != "1" {
+		t.Error("expected fields", d.Fields)
+	}
+	if link := blocks[1].Paragraph; link.Text != "Link to something" {
+		t.Error("expected field instructions to be hidden", link)
+	}
+
+	if len(d.Footnotes) != 1 || d.Footnotes[0].Text != "Here is my footnote" || len(d.Endnotes) != 1 || d.Endnotes[0].Text != "My endnote" {
+		t.Error("expected notes", d.Footnotes, d.Endnotes)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteJSON(&buf); err != nil {
+		t.Fatal("expected to write JSON", err)
+	}
+	var decoded Document
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.SchemaVersion != JSONSchemaVersion || len(decoded.Stories) != len(d.Stories) ||
+		len(decoded.Fields) != len(d.Fields) || decoded.Stories[0].Sections[0].Blocks[0].Paragraph.Text != title.Text {
+		t.Error("expected JSON to round trip", err)
+	}
+}
+
+func TestStoryText(t *testing.T) {
+	story := &Story{Sections: []Section{{Blocks: []Block{
+		{Type: BlockParagraph, Paragraph: &Paragraph{CP: 10, Text: "one"}},
+		{Type: BlockTable, Table: &Table{Rows: []TableRow{{Cells: []TableCell{{Paragraphs: []Paragraph{{CP: 14, Text: "two"}}}}}}}},
+		{Type: BlockParagraph, Paragraph: &Paragraph{CP: 18, Text: "three"}},
+	}}}}
+	if s := storyText(story, 10, 18); s != "one\ntwo" {
+		t.Error("expected text of paragraphs in range", s)
+	}
+}
+
+func TestCharacterProperties(t *testing.T) {
+	bold, italic := []byte{0x35, 0x08, 0x01}, []byte{0x36, 0x08, 0x01} // sprmCFBold and sprmCFItalic
+	b := &docBuilder{runs: []cpRun{{cpStart: 0, cpEnd: 5, grpprl: bold}, {cpStart: 5, cpEnd: 10, grpprl: italic}}}
+	for _, test := range []struct {
+		cp           int
+		bold, italic bool
+	}{{12, false, false}, {7, false, true}, {2, true, false}, {10, false, false}, {9, false, true}} {
+		if c := b.characterProperties(test.cp, defaultChp()); c.bold != test.bold || c.italic != test.italic {
+			t.Error("expected the properties of the run at", test.cp, c.bold, c.italic)
+		}
+	}
+}
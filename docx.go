@@ -0,0 +1,979 @@
+package doc2txt
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	nsWordML        = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	nsRelationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsPackageRels   = "http://schemas.openxmlformats.org/package/2006/relationships"
+	nsContentTypes  = "http://schemas.openxmlformats.org/package/2006/content-types"
+	nsDrawing       = "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"
+	nsDrawingML     = "http://schemas.openxmlformats.org/drawingml/2006/main"
+	nsPicture       = "http://schemas.openxmlformats.org/drawingml/2006/picture"
+	relTypeBase     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	contentTypeBase = "application/vnd.openxmlformats-officedocument.wordprocessingml."
+)
+
+// WriteDOCX writes the document as an Office Open XML .docx package containing
+// the main story, styles, lists, pictures, footnotes, endnotes and comments. Pictures in
+// PICT format are left out
+func (d *Document) WriteDOCX(w io.Writer) error {
+	x := newDocxWriter(d)
+	parts := []struct {
+		name string
+		v    interface{}
+	}{
+		{"[Content_Types].xml", x.contentTypes()},
+		{"_rels/.rels", docxRels{Xmlns: nsPackageRels, Rels: []docxRel{{ID: "rId1", Type: relTypeBase + "officeDocument", Target: "word/document.xml"}}}},
+		{"word/_rels/document.xml.rels", x.documentRels()},
+		{"word/document.xml", x.document()},
+		{"word/styles.xml", x.styles()},
+		{"word/numbering.xml", x.numbering()},
+		{"word/footnotes.xml", x.notes("w:footnotes", "w:footnote", d.Footnotes)},
+		{"word/endnotes.xml", x.notes("w:endnotes", "w:endnote", d.Endnotes)},
+		{"word/comments.xml", x.comments()},
+	}
+
+	z := zip.NewWriter(w)
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header); err != nil {
+			return err
+		}
+		if err := xml.NewEncoder(f).Encode(part.v); err != nil {
+			return err
+		}
+	}
+	for _, m := range x.media {
+		f, err := z.Create("word/" + m.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(m.data); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// docxWriter converts the document model to WordprocessingML
+type docxWriter struct {
+	d        *Document
+	styleIDs map[string]string // style name to styleId
+	refs     map[int][]docxRef // note and comment references, and pictures, by paragraph CP
+	revision int               // the next w:id for an insertion or deletion
+	media    []docxMedia
+	pictures map[int]string // the relationship ID of the media of each picture, by index in Pictures
+	lists    map[int]int    // list ID to abstractNumId
+	nums     map[int]bool   // the list instances that have a w:num
+}
+
+// docxMedia is an image part, named media/imageN.ext
+type docxMedia struct {
+	name, extension, contentType string
+	rel                          string
+	data                         []byte
+}
+
+// the extensions and content types of the media parts by picture format
+var docxMediaTypes = map[string][2]string{
+	PicturePNG:  {"png", "image/png"},
+	PictureJPEG: {"jpeg", "image/jpeg"},
+	PictureEMF:  {"emf", "image/x-emf"},
+	PictureWMF:  {"wmf", "image/x-wmf"},
+	PictureDIB:  {"bmp", "image/bmp"},
+	PictureTIFF: {"tiff", "image/tiff"},
+}
+
+// the relationships of document.xml that come before those of the media
+const docxPartRels = 5
+
+func newDocxWriter(d *Document) *docxWriter {
+	x := &docxWriter{d: d, styleIDs: map[string]string{}, refs: map[int][]docxRef{}, pictures: map[int]string{},
+		lists: map[int]int{}, nums: map[int]bool{}}
+	used := map[string]bool{}
+	for _, s := range d.Styles {
+		id := styleID(s.Name)
+		for i := 2; used[id]; i++ {
+			id = styleID(s.Name) + strconv.Itoa(i)
+		}
+		used[id] = true
+		x.styleIDs[s.Name] = id
+	}
+
+	// only the main story is written, so only its pictures have media
+	var main Story
+	for _, story := range d.Stories {
+		if story.Type == StoryMain {
+			main = story
+		}
+	}
+	for i, p := range d.Pictures {
+		typ, ok := docxMediaTypes[p.Format]
+		if !ok || len(p.Data) == 0 || p.CP < main.CP || p.CP >= main.CP+main.Length {
+			continue
+		}
+		data := p.Data
+		if p.Format == PictureDIB {
+			if data = bitmapFile(data); data == nil {
+				continue
+			}
+		}
+		n := len(x.media) + 1
+		m := docxMedia{name: "media/image" + strconv.Itoa(n) + "." + typ[0], extension: typ[0], contentType: typ[1],
+			rel: "rId" + strconv.Itoa(docxPartRels+n), data: data}
+		x.media = append(x.media, m)
+		x.pictures[i] = m.rel
+	}
+
+	for i, l := range d.Lists {
+		x.lists[l.ID] = i
+	}
+	for _, instance := range d.ListInstances {
+		if _, ok := x.lists[instance.ListID]; ok {
+			x.nums[instance.ID] = true
+		}
+	}
+	return x
+}
+
+// add a BITMAPFILEHEADER to a DIB. It gives the offset of the pixels, which follow the header
+// and color table of the DIB. Returns nil if the DIB is too short
+func bitmapFile(dib []byte) []byte {
+	if len(dib) < 12 {
+		return nil
+	}
+	headerSize := int(binary.LittleEndian.Uint32(dib))
+	var colors, colorSize int
+	if headerSize == 12 { // BITMAPCOREHEADER, with RGBTRIPLE colors
+		if bitCount := binary.LittleEndian.Uint16(dib[10:]); bitCount <= 8 {
+			colors = 1 << bitCount
+		}
+		colorSize = 3
+	} else {
+		if headerSize < 40 || len(dib) < 40 {
+			return nil
+		}
+		bitCount, compression := binary.LittleEndian.Uint16(dib[14:]), binary.LittleEndian.Uint32(dib[16:])
+		colors, colorSize = int(binary.LittleEndian.Uint32(dib[32:])), 4
+		if colors == 0 && bitCount <= 8 {
+			colors = 1 << bitCount
+		}
+		if headerSize == 40 && compression == 3 { // the masks of BI_BITFIELDS follow a BITMAPINFOHEADER
+			headerSize += 12
+		}
+	}
+	b := make([]byte, 14, 14+len(dib))
+	b[0], b[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(b[2:], uint32(14+len(dib)))
+	binary.LittleEndian.PutUint32(b[10:], uint32(14+headerSize+colors*colorSize))
+	return append(b, dib...)
+}
+
+// build a styleId from a style name by keeping only letters and digits, as Word does
+func styleID(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+	if id == "" {
+		return "Style"
+	}
+	return id
+}
+
+type docxRels struct {
+	XMLName xml.Name  `xml:"Relationships"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Rels    []docxRel `xml:"Relationship"`
+}
+
+type docxRel struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type docxContentTypes struct {
+	XMLName   xml.Name          `xml:"Types"`
+	Xmlns     string            `xml:"xmlns,attr"`
+	Defaults  []docxDefaultType `xml:"Default"`
+	Overrides []docxOverride    `xml:"Override"`
+}
+
+type docxDefaultType struct {
+	Extension   string `xml:"Extension,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+type docxOverride struct {
+	PartName    string `xml:"PartName,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+func (x *docxWriter) contentTypes() docxContentTypes {
+	types := docxContentTypes{Xmlns: nsContentTypes,
+		Defaults: []docxDefaultType{
+			{"rels", "application/vnd.openxmlformats-package.relationships+xml"},
+			{"xml", "application/xml"},
+		},
+		Overrides: []docxOverride{
+			{"/word/document.xml", contentTypeBase + "document.main+xml"},
+			{"/word/styles.xml", contentTypeBase + "styles+xml"},
+			{"/word/numbering.xml", contentTypeBase + "numbering+xml"},
+			{"/word/footnotes.xml", contentTypeBase + "footnotes+xml"},
+			{"/word/endnotes.xml", contentTypeBase + "endnotes+xml"},
+			{"/word/comments.xml", contentTypeBase + "comments+xml"},
+		},
+	}
+	added := map[string]bool{}
+	for _, m := range x.media {
+		if !added[m.extension] {
+			types.Defaults = append(types.Defaults, docxDefaultType{m.extension, m.contentType})
+			added[m.extension] = true
+		}
+	}
+	return types
+}
+
+func (x *docxWriter) documentRels() docxRels {
+	rels := docxRels{Xmlns: nsPackageRels, Rels: []docxRel{
+		{"rId1", relTypeBase + "styles", "styles.xml"},
+		{"rId2", relTypeBase + "numbering", "numbering.xml"},
+		{"rId3", relTypeBase + "footnotes", "footnotes.xml"},
+		{"rId4", relTypeBase + "endnotes", "endnotes.xml"},
+		{"rId5", relTypeBase + "comments", "comments.xml"},
+	}}
+	for _, m := range x.media {
+		rels.Rels = append(rels.Rels, docxRel{m.rel, relTypeBase + "image", m.name})
+	}
+	return rels
+}
+
+// WordprocessingML elements. The w: prefix is written as part of the names, and
+// the namespace is declared on the root element of each part
+type docxDocument struct {
+	XMLName xml.Name `xml:"w:document"`
+	W       string   `xml:"xmlns:w,attr"`
+	R       string   `xml:"xmlns:r,attr"`
+	WP      string   `xml:"xmlns:wp,attr"`
+	A       string   `xml:"xmlns:a,attr"`
+	Pic     string   `xml:"xmlns:pic,attr"`
+	Body    docxBody `xml:"w:body"`
+}
+
+type docxBody struct {
+	Content []interface{} // docxParagraph and docxTable
+	SectPr  docxSectPr    `xml:"w:sectPr"`
+}
+
+type docxSectPr struct{}
+
+type docxVal struct {
+	Val string `xml:"w:val,attr"`
+}
+
+type docxParagraph struct {
+	XMLName xml.Name      `xml:"w:p"`
+	PPr     *docxPPr      `xml:"w:pPr"`
+	Content []interface{} // docxRun and docxChange
+}
+
+type docxPPr struct {
+	PStyle *docxVal    `xml:"w:pStyle"`
+	NumPr  *docxNumPr  `xml:"w:numPr"`
+	Jc     *docxVal    `xml:"w:jc"`
+	SectPr *docxSectPr `xml:"w:sectPr"`
+}
+
+type docxNumPr struct {
+	Ilvl  docxVal `xml:"w:ilvl"`
+	NumID docxVal `xml:"w:numId"`
+}
+
+type docxRun struct {
+	XMLName xml.Name      `xml:"w:r"`
+	RPr     *docxRPr      `xml:"w:rPr"`
+	Content []interface{} // docxText, docxBreak, docxTab, docxDrawing and references
+}
+
+type docxRPr struct {
+	RFonts    *docxFonts `xml:"w:rFonts"`
+	B         *struct{}  `xml:"w:b"`
+	I         *struct{}  `xml:"w:i"`
+	Caps      *struct{}  `xml:"w:caps"`
+	SmallCaps *struct{}  `xml:"w:smallCaps"`
+	Strike    *struct{}  `xml:"w:strike"`
+	Vanish    *struct{}  `xml:"w:vanish"`
+	Color     *docxVal   `xml:"w:color"`
+	Sz        *docxVal   `xml:"w:sz"`
+	U         *docxVal   `xml:"w:u"`
+	VertAlign *docxVal   `xml:"w:vertAlign"`
+}
+
+type docxFonts struct {
+	ASCII string `xml:"w:ascii,attr"`
+	HAnsi string `xml:"w:hAnsi,attr"`
+}
+
+type docxText struct {
+	XMLName xml.Name
+	Space   string `xml:"xml:space,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type docxBreak struct {
+	XMLName xml.Name `xml:"w:br"`
+	Type    string   `xml:"w:type,attr,omitempty"`
+}
+
+type docxTab struct {
+	XMLName xml.Name `xml:"w:tab"`
+}
+
+type docxReference struct {
+	XMLName xml.Name
+	ID      int `xml:"w:id,attr"`
+}
+
+type docxEmpty struct {
+	XMLName xml.Name
+}
+
+// docxChange is an insertion or deletion revision (w:ins or w:del)
+type docxChange struct {
+	XMLName xml.Name
+	ID      int       `xml:"w:id,attr"`
+	Author  string    `xml:"w:author,attr"`
+	Date    string    `xml:"w:date,attr,omitempty"`
+	Runs    []docxRun `xml:"w:r"`
+}
+
+type docxTable struct {
+	XMLName xml.Name      `xml:"w:tbl"`
+	TblPr   docxTblPr     `xml:"w:tblPr"`
+	Grid    []docxGridCol `xml:"w:tblGrid>w:gridCol"`
+	Rows    []docxRow     `xml:"w:tr"`
+}
+
+type docxTblPr struct {
+	TblW docxWidth `xml:"w:tblW"`
+}
+
+type docxWidth struct {
+	W    int    `xml:"w:w,attr"`
+	Type string `xml:"w:type,attr"`
+}
+
+type docxGridCol struct{}
+
+type docxRow struct {
+	Cells []docxCell `xml:"w:tc"`
+}
+
+type docxCell struct {
+	Paragraphs []docxParagraph `xml:"w:p"`
+}
+
+func (x *docxWriter) document() docxDocument {
+	doc := docxDocument{W: nsWordML, R: nsRelationships, WP: nsDrawing, A: nsDrawingML, Pic: nsPicture}
+	for _, story := range x.d.Stories {
+		if story.Type != StoryMain {
+			continue
+		}
+		x.references(story)
+		for i, section := range story.Sections {
+			var content []interface{}
+			for _, block := range section.Blocks {
+				if block.Table != nil {
+					content = append(content, x.table(block.Table))
+				} else {
+					content = append(content, x.paragraph(block.Paragraph))
+				}
+			}
+			if i+1 < len(story.Sections) { // every section but the last ends with a paragraph holding its properties
+				var last docxParagraph
+				ok := false
+				if len(content) > 0 {
+					last, ok = content[len(content)-1].(docxParagraph)
+				}
+				if !ok {
+					last = docxParagraph{}
+					content = append(content, last)
+				}
+				if last.PPr == nil {
+					last.PPr = &docxPPr{}
+				}
+				last.PPr.SectPr = &docxSectPr{}
+				content[len(content)-1] = last
+			}
+			doc.Body.Content = append(doc.Body.Content, content...)
+		}
+	}
+	return doc
+}
+
+func (x *docxWriter) table(t *Table) docxTable {
+	tbl := docxTable{TblPr: docxTblPr{TblW: docxWidth{Type: "auto"}}}
+	columns := 0
+	for _, row := range t.Rows {
+		var r docxRow
+		for _, cell := range row.Cells {
+			var c docxCell
+			for i := range cell.Paragraphs {
+				c.Paragraphs = append(c.Paragraphs, x.paragraph(&cell.Paragraphs[i]))
+			}
+			if len(c.Paragraphs) == 0 { // a cell must contain at least one paragraph
+				c.Paragraphs = []docxParagraph{{}}
+			}
+			r.Cells = append(r.Cells, c)
+		}
+		if len(r.Cells) == 0 {
+			r.Cells = []docxCell{{Paragraphs: []docxParagraph{{}}}}
+		}
+		if len(r.Cells) > columns {
+			columns = len(r.Cells)
+		}
+		tbl.Rows = append(tbl.Rows, r)
+	}
+	tbl.Grid = make([]docxGridCol, columns)
+	return tbl
+}
+
+func (x *docxWriter) paragraph(p *Paragraph) docxParagraph {
+	para := docxParagraph{}
+	list := p.List != nil && x.nums[p.List.Instance]
+	if id, ok := x.styleIDs[p.Style]; ok || list || p.Alignment != "" {
+		para.PPr = &docxPPr{}
+		if ok {
+			para.PPr.PStyle = &docxVal{id}
+		}
+		if list {
+			para.PPr.NumPr = &docxNumPr{docxVal{strconv.Itoa(p.List.Level)}, docxVal{strconv.Itoa(p.List.Instance)}}
+		}
+		if jc := docxAlignment(p.Alignment); jc != "" {
+			para.PPr.Jc = &docxVal{jc}
+		}
+	}
+
+	refs := x.refs[p.CP]
+	for i, run := range p.Runs {
+		r := docxRun{RPr: docxRunProperties(run), Content: docxRunContent(run.Text, run.Deleted)}
+		switch {
+		case run.Deleted:
+			para.Content = append(para.Content, x.change("w:del", run, RevisionDeletion, r))
+		case run.Inserted:
+			para.Content = append(para.Content, x.change("w:ins", run, RevisionInsertion, r))
+		default:
+			para.Content = append(para.Content, r)
+		}
+
+		// the note and comment reference characters and the pictures are not part of the runs,
+		// so add them after the last run that begins before them
+		next := -1
+		if i+1 < len(p.Runs) {
+			next = p.Runs[i+1].CP
+		}
+		for len(refs) > 0 && (next < 0 || refs[0].cp < next) {
+			para.Content = append(para.Content, refs[0].run)
+			refs = refs[1:]
+		}
+	}
+	for _, ref := range refs {
+		para.Content = append(para.Content, ref.run)
+	}
+	return para
+}
+
+type docxRef struct {
+	cp  int
+	run docxRun
+}
+
+// group the note and comment references and the pictures of the main story by the paragraph
+// they are in
+func (x *docxWriter) references(story Story) {
+	var paragraphs []int
+	for _, section := range story.Sections {
+		for _, block := range section.Blocks {
+			if block.Paragraph != nil {
+				paragraphs = append(paragraphs, block.Paragraph.CP)
+				continue
+			}
+			for _, row := range block.Table.Rows {
+				for _, cell := range row.Cells {
+					for _, p := range cell.Paragraphs {
+						paragraphs = append(paragraphs, p.CP)
+					}
+				}
+			}
+		}
+	}
+
+	var refs []docxRef
+	add := func(cp int, name string, id int, superscript bool) {
+		r := docxRun{Content: []interface{}{docxReference{XMLName: xml.Name{Local: name}, ID: id}}}
+		if superscript {
+			r.RPr = &docxRPr{VertAlign: &docxVal{"superscript"}}
+		}
+		refs = append(refs, docxRef{cp, r})
+	}
+	for i, n := range x.d.Footnotes {
+		add(n.CP, "w:footnoteReference", i+1, true)
+	}
+	for i, n := range x.d.Endnotes {
+		add(n.CP, "w:endnoteReference", i+1, true)
+	}
+	for i, c := range x.d.Comments {
+		add(c.CP, "w:commentReference", i, false)
+	}
+	for i, p := range x.d.Pictures {
+		if rel, ok := x.pictures[i]; ok {
+			refs = append(refs, docxRef{p.CP, docxRun{Content: []interface{}{x.drawing(i, p, rel)}}})
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].cp < refs[j].cp })
+
+	for _, ref := range refs {
+		i := sort.SearchInts(paragraphs, ref.cp+1) - 1 // the last paragraph that begins at or before the reference
+		if i >= 0 {
+			x.refs[paragraphs[i]] = append(x.refs[paragraphs[i]], ref)
+		}
+	}
+}
+
+// DrawingML pictures. A picture that has a floating shape is anchored, and positioned like the
+// shape, and other pictures are inline
+type docxDrawing struct {
+	XMLName xml.Name    `xml:"w:drawing"`
+	Inline  *docxInline `xml:"wp:inline"`
+	Anchor  *docxAnchor `xml:"wp:anchor"`
+}
+
+type docxInline struct {
+	Extent  docxExtent      `xml:"wp:extent"`
+	DocPr   docxDocPr       `xml:"wp:docPr"`
+	Graphic docxGraphicData `xml:"a:graphic>a:graphicData"`
+}
+
+type docxAnchor struct {
+	SimplePos        int             `xml:"simplePos,attr"`
+	RelativeHeight   int             `xml:"relativeHeight,attr"`
+	BehindDoc        int             `xml:"behindDoc,attr"`
+	Locked           int             `xml:"locked,attr"`
+	LayoutInCell     int             `xml:"layoutInCell,attr"`
+	AllowOverlap     int             `xml:"allowOverlap,attr"`
+	Position         docxPoint       `xml:"wp:simplePos"`
+	PositionH        docxPosition    `xml:"wp:positionH"`
+	PositionV        docxPosition    `xml:"wp:positionV"`
+	Extent           docxExtent      `xml:"wp:extent"`
+	WrapNone         *struct{}       `xml:"wp:wrapNone"`
+	WrapSquare       *docxWrapSquare `xml:"wp:wrapSquare"`
+	WrapTopAndBottom *struct{}       `xml:"wp:wrapTopAndBottom"`
+	DocPr            docxDocPr       `xml:"wp:docPr"`
+	Graphic          docxGraphicData `xml:"a:graphic>a:graphicData"`
+}
+
+type docxPoint struct {
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+}
+
+type docxPosition struct {
+	RelativeFrom string `xml:"relativeFrom,attr"`
+	Offset       int    `xml:"wp:posOffset"`
+}
+
+type docxExtent struct {
+	Cx int `xml:"cx,attr"`
+	Cy int `xml:"cy,attr"`
+}
+
+type docxWrapSquare struct {
+	WrapText string `xml:"wrapText,attr"`
+}
+
+type docxDocPr struct {
+	ID    int    `xml:"id,attr"`
+	Name  string `xml:"name,attr"`
+	Descr string `xml:"descr,attr,omitempty"`
+}
+
+type docxGraphicData struct {
+	URI      string     `xml:"uri,attr"`
+	CNvPr    docxDocPr  `xml:"pic:pic>pic:nvPicPr>pic:cNvPr"`
+	CNvPicPr struct{}   `xml:"pic:pic>pic:nvPicPr>pic:cNvPicPr"`
+	Blip     docxBlip   `xml:"pic:pic>pic:blipFill>a:blip"`
+	FillRect struct{}   `xml:"pic:pic>pic:blipFill>a:stretch>a:fillRect"`
+	Offset   docxPoint  `xml:"pic:pic>pic:spPr>a:xfrm>a:off"`
+	Extent   docxExtent `xml:"pic:pic>pic:spPr>a:xfrm>a:ext"`
+	Geometry docxGeom   `xml:"pic:pic>pic:spPr>a:prstGeom"`
+}
+
+type docxBlip struct {
+	Embed string `xml:"r:embed,attr"`
+}
+
+type docxGeom struct {
+	Prst  string   `xml:"prst,attr"`
+	AvLst struct{} `xml:"a:avLst"`
+}
+
+// the number of EMUs in a twip
+const emuPerTwip = 635
+
+// the drawing of the picture at index i of Pictures, whose media has the relationship rel
+func (x *docxWriter) drawing(i int, p Picture, rel string) docxDrawing {
+	name := p.Name
+	if name == "" {
+		name = "Picture " + strconv.Itoa(i+1)
+	}
+	docPr := docxDocPr{ID: i + 1, Name: name, Descr: p.Description}
+	width, height := p.Width, p.Height
+	var shape *Shape
+	for j := range x.d.Shapes {
+		if p.ShapeID != 0 && x.d.Shapes[j].ID == p.ShapeID {
+			shape = &x.d.Shapes[j]
+			width, height = shape.Width, shape.Height
+			break
+		}
+	}
+	extent := docxExtent{width * emuPerTwip, height * emuPerTwip}
+	graphic := docxGraphicData{URI: nsPicture, CNvPr: docxDocPr{ID: i + 1, Name: name}, Blip: docxBlip{rel}, Extent: extent,
+		Geometry: docxGeom{Prst: "rect"}}
+	if shape == nil {
+		return docxDrawing{Inline: &docxInline{Extent: extent, DocPr: docPr, Graphic: graphic}}
+	}
+
+	// the origins of DOCX have no paragraph for horizontal positions and no column for vertical
+	// ones, so those are written as the nearest origin
+	horizontal, vertical := shape.HorizontalOrigin, shape.VerticalOrigin
+	if horizontal == "" || horizontal == ShapeOriginParagraph {
+		horizontal = ShapeOriginColumn
+	}
+	if vertical == "" || vertical == ShapeOriginColumn {
+		vertical = ShapeOriginParagraph
+	}
+	anchor := &docxAnchor{RelativeHeight: i + 1, LayoutInCell: 1, AllowOverlap: 1,
+		PositionH: docxPosition{horizontal, shape.Left * emuPerTwip}, PositionV: docxPosition{vertical, shape.Top * emuPerTwip},
+		Extent: extent, DocPr: docPr, Graphic: graphic}
+	if shape.BehindText {
+		anchor.BehindDoc = 1
+	}
+	switch shape.Wrap {
+	case ShapeWrapNone:
+		anchor.WrapNone = &struct{}{}
+	case ShapeWrapTopBottom:
+		anchor.WrapTopAndBottom = &struct{}{}
+	default: // tight and through wrapping need a polygon, so they are written as square
+		anchor.WrapSquare = &docxWrapSquare{"bothSides"}
+	}
+	return docxDrawing{Anchor: anchor}
+}
+
+// wrap a run in a w:ins or w:del using the author and date of the revision it belongs to
+func (x *docxWriter) change(name string, run Run, typ string, r docxRun) docxChange {
+	c := docxChange{XMLName: xml.Name{Local: name}, ID: x.revision, Runs: []docxRun{r}}
+	x.revision++
+	for _, rev := range x.d.Revisions {
+		if rev.Type == typ && run.CP >= rev.CP && run.CP < rev.CP+rev.Length {
+			c.Author = rev.Author
+			if rev.Date != nil {
+				c.Date = rev.Date.Format(time.RFC3339)
+			}
+			break
+		}
+	}
+	return c
+}
+
+func docxRunProperties(run Run) *docxRPr {
+	on := &struct{}{}
+	rPr := &docxRPr{}
+	empty := *rPr
+	if run.Font != "" {
+		rPr.RFonts = &docxFonts{run.Font, run.Font}
+	}
+	if run.Bold {
+		rPr.B = on
+	}
+	if run.Italic {
+		rPr.I = on
+	}
+	if run.Caps {
+		rPr.Caps = on
+	}
+	if run.SmallCaps {
+		rPr.SmallCaps = on
+	}
+	if run.Strike {
+		rPr.Strike = on
+	}
+	if run.Hidden {
+		rPr.Vanish = on
+	}
+	if run.Color != "" {
+		rPr.Color = &docxVal{run.Color}
+	}
+	if run.Size > 0 {
+		rPr.Sz = &docxVal{strconv.Itoa(int(run.Size * 2))} // in half points
+	}
+	if run.Underline {
+		rPr.U = &docxVal{"single"}
+	}
+	if run.VerticalAlign != "" {
+		rPr.VertAlign = &docxVal{run.VerticalAlign}
+	}
+	if *rPr == empty {
+		return nil
+	}
+	return rPr
+}
+
+// split text into w:t elements, tabs and breaks. Deleted text is written as w:delText
+func docxRunContent(s string, deleted bool) []interface{} {
+	name := "w:t"
+	if deleted {
+		name = "w:delText"
+	}
+	var content []interface{}
+	var text []rune
+	flush := func() {
+		if len(text) > 0 {
+			content = append(content, docxText{XMLName: xml.Name{Local: name}, Space: "preserve", Text: string(text)})
+			text = nil
+		}
+	}
+	for _, r := range s {
+		switch r {
+		case '\t':
+			flush()
+			content = append(content, docxTab{})
+		case '\n':
+			flush()
+			content = append(content, docxBreak{})
+		case '\f':
+			flush()
+			content = append(content, docxBreak{Type: "page"})
+		default:
+			text = append(text, r)
+		}
+	}
+	flush()
+	return content
+}
+
+func docxAlignment(alignment string) string {
+	switch alignment {
+	case "center":
+		return "center"
+	case "right":
+		return "right"
+	case "justify":
+		return "both"
+	case "distribute":
+		return "distribute"
+	}
+	return ""
+}
+
+type docxStyles struct {
+	XMLName xml.Name    `xml:"w:styles"`
+	W       string      `xml:"xmlns:w,attr"`
+	Styles  []docxStyle `xml:"w:style"`
+}
+
+type docxStyle struct {
+	Type    string   `xml:"w:type,attr"`
+	Default string   `xml:"w:default,attr,omitempty"`
+	ID      string   `xml:"w:styleId,attr"`
+	Name    docxVal  `xml:"w:name"`
+	BasedOn *docxVal `xml:"w:basedOn"`
+}
+
+func (x *docxWriter) styles() docxStyles {
+	styles := docxStyles{W: nsWordML}
+	for _, s := range x.d.Styles {
+		if s.Type == "" {
+			continue
+		}
+		st := docxStyle{Type: s.Type, ID: x.styleIDs[s.Name], Name: docxVal{s.Name}}
+		if s.ID == istdNormal || s.ID == istdDefaultChar {
+			st.Default = "1"
+		}
+		if id, ok := x.styleIDs[s.BasedOn]; ok {
+			st.BasedOn = &docxVal{id}
+		}
+		styles.Styles = append(styles.Styles, st)
+	}
+	return styles
+}
+
+type docxNumbering struct {
+	XMLName      xml.Name          `xml:"w:numbering"`
+	W            string            `xml:"xmlns:w,attr"`
+	AbstractNums []docxAbstractNum `xml:"w:abstractNum"`
+	Nums         []docxNum         `xml:"w:num"`
+}
+
+type docxAbstractNum struct {
+	ID     int       `xml:"w:abstractNumId,attr"`
+	Levels []docxLvl `xml:"w:lvl"`
+}
+
+type docxLvl struct {
+	Ilvl    int         `xml:"w:ilvl,attr"`
+	Start   docxVal     `xml:"w:start"`
+	NumFmt  docxVal     `xml:"w:numFmt"`
+	Suff    *docxVal    `xml:"w:suff"`
+	LvlText docxVal     `xml:"w:lvlText"`
+	LvlJc   docxVal     `xml:"w:lvlJc"`
+	Ind     *docxIndent `xml:"w:pPr>w:ind"`
+}
+
+type docxIndent struct {
+	Left      int `xml:"w:left,attr"`
+	Hanging   int `xml:"w:hanging,attr,omitempty"`
+	FirstLine int `xml:"w:firstLine,attr,omitempty"`
+}
+
+type docxNum struct {
+	ID            int               `xml:"w:numId,attr"`
+	AbstractNumID docxVal           `xml:"w:abstractNumId"`
+	Overrides     []docxLvlOverride `xml:"w:lvlOverride"`
+}
+
+type docxLvlOverride struct {
+	Ilvl          int      `xml:"w:ilvl,attr"`
+	StartOverride *docxVal `xml:"w:startOverride"`
+	Lvl           *docxLvl `xml:"w:lvl"`
+}
+
+// build numbering.xml, with an abstract numbering for each list and a numbering for each list
+// instance, which paragraphs refer to by its ID
+func (x *docxWriter) numbering() docxNumbering {
+	n := docxNumbering{W: nsWordML}
+	for i, l := range x.d.Lists {
+		a := docxAbstractNum{ID: i}
+		for level, definition := range l.Levels {
+			a.Levels = append(a.Levels, docxLevel(level, definition))
+		}
+		n.AbstractNums = append(n.AbstractNums, a)
+	}
+	for _, instance := range x.d.ListInstances {
+		if !x.nums[instance.ID] {
+			continue
+		}
+		num := docxNum{ID: instance.ID, AbstractNumID: docxVal{strconv.Itoa(x.lists[instance.ListID])}}
+		for _, o := range instance.Overrides {
+			override := docxLvlOverride{Ilvl: o.Level, StartOverride: &docxVal{strconv.Itoa(o.Start)}}
+			if o.Definition != nil {
+				lvl := docxLevel(o.Level, *o.Definition)
+				override.Lvl = &lvl
+			}
+			num.Overrides = append(num.Overrides, override)
+		}
+		n.Nums = append(n.Nums, num)
+	}
+	return n
+}
+
+func docxLevel(ilvl int, l ListLevel) docxLvl {
+	lvl := docxLvl{Ilvl: ilvl, Start: docxVal{strconv.Itoa(l.Start)}, NumFmt: docxVal{l.Format}, LvlText: docxVal{l.Text},
+		LvlJc: docxVal{"left"}}
+	if l.Follow != "tab" {
+		lvl.Suff = &docxVal{l.Follow}
+	}
+	if jc := docxAlignment(l.Alignment); jc != "" {
+		lvl.LvlJc = docxVal{jc}
+	}
+	if l.Indent != 0 || l.FirstLine != 0 {
+		lvl.Ind = &docxIndent{Left: l.Indent}
+		if l.FirstLine < 0 {
+			lvl.Ind.Hanging = -l.FirstLine
+		} else {
+			lvl.Ind.FirstLine = l.FirstLine
+		}
+	}
+	return lvl
+}
+
+type docxNotes struct {
+	XMLName xml.Name
+	W       string `xml:"xmlns:w,attr"`
+	Notes   []docxNote
+}
+
+type docxNote struct {
+	XMLName    xml.Name
+	Type       string          `xml:"w:type,attr,omitempty"`
+	ID         int             `xml:"w:id,attr"`
+	Paragraphs []docxParagraph `xml:"w:p"`
+}
+
+// build footnotes.xml or endnotes.xml. The separators are expected by Word before the notes
+func (x *docxWriter) notes(root, name string, notes []Note) docxNotes {
+	n := docxNotes{XMLName: xml.Name{Local: root}, W: nsWordML}
+	separator := func(typ string, id int, mark string) docxNote {
+		return docxNote{XMLName: xml.Name{Local: name}, Type: typ, ID: id, Paragraphs: []docxParagraph{{Content: []interface{}{
+			docxRun{Content: []interface{}{docxEmpty{XMLName: xml.Name{Local: mark}}}}}}}}
+	}
+	n.Notes = append(n.Notes, separator("separator", -1, "w:separator"), separator("continuationSeparator", 0, "w:continuationSeparator"))
+	ref := name + "Ref" // the note reference mark, w:footnoteRef or w:endnoteRef
+	for i, note := range notes {
+		paragraphs := docxLines(note.Text)
+		paragraphs[0].Content = append([]interface{}{docxRun{RPr: &docxRPr{VertAlign: &docxVal{"superscript"}},
+			Content: []interface{}{docxEmpty{XMLName: xml.Name{Local: ref}}}}, docxRun{Content: docxRunContent(" ", false)}}, paragraphs[0].Content...)
+		n.Notes = append(n.Notes, docxNote{XMLName: xml.Name{Local: name}, ID: i + 1, Paragraphs: paragraphs})
+	}
+	return n
+}
+
+type docxComments struct {
+	XMLName  xml.Name      `xml:"w:comments"`
+	W        string        `xml:"xmlns:w,attr"`
+	Comments []docxComment `xml:"w:comment"`
+}
+
+type docxComment struct {
+	ID         int             `xml:"w:id,attr"`
+	Author     string          `xml:"w:author,attr"`
+	Initials   string          `xml:"w:initials,attr,omitempty"`
+	Paragraphs []docxParagraph `xml:"w:p"`
+}
+
+func (x *docxWriter) comments() docxComments {
+	c := docxComments{W: nsWordML}
+	for i, comment := range x.d.Comments {
+		c.Comments = append(c.Comments, docxComment{ID: i, Author: comment.Author, Initials: comment.Initials, Paragraphs: docxLines(comment.Text)})
+	}
+	return c
+}
+
+// one paragraph for each line of text
+func docxLines(s string) []docxParagraph {
+	var paragraphs []docxParagraph
+	for _, line := range strings.Split(s, "\n") {
+		p := docxParagraph{}
+		if line != "" {
+			p.Content = []interface{}{docxRun{Content: docxRunContent(line, false)}}
+		}
+		paragraphs = append(paragraphs, p)
+	}
+	return paragraphs
+}
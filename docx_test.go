@@ -0,0 +1,147 @@
+package doc2txt
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOCX(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	d, err := ParseDocument(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+	var buf bytes.Buffer
+	if err := d.WriteDOCX(&buf); err != nil {
+		t.Fatal("expected to write docx", err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("expected a zip file", err)
+	}
+
+	parts := map[string]string{}
+	for _, file := range z.File {
+		r, _ := file.Open()
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		parts[file.Name] = string(b)
+
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal("expected well formed XML", file.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/_rels/document.xml.rels", "word/document.xml",
+		"word/styles.xml", "word/numbering.xml", "word/footnotes.xml", "word/endnotes.xml", "word/comments.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Error("expected part", name)
+		}
+	}
+
+	doc := parts["word/document.xml"]
+	for _, s := range []string{`<w:pStyle w:val="NormalWeb"></w:pStyle><w:jc w:val="center"></w:jc>`, `<w:b></w:b><w:color w:val="000080"></w:color><w:sz w:val="60"></w:sz>`,
+		`>Name Here in Big</w:t>`, `<w:tblGrid><w:gridCol></w:gridCol><w:gridCol></w:gridCol></w:tblGrid>`, `<w:footnoteReference w:id="1">`, `<w:endnoteReference w:id="1">`} {
+		if !strings.Contains(doc, s) {
+			t.Error("expected document to contain", s)
+		}
+	}
+	if s := `<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="Heading 1"></w:name><w:basedOn w:val="Normal"></w:basedOn></w:style>`; !strings.Contains(parts["word/styles.xml"], s) {
+		t.Error("expected styles to contain", s)
+	}
+	if s := `<w:pPr><w:pStyle w:val="NormalWeb"></w:pStyle><w:numPr><w:ilvl w:val="0"></w:ilvl><w:numId w:val="2"></w:numId></w:numPr></w:pPr>`; !strings.Contains(doc, s) {
+		t.Error("expected a numbered paragraph", s)
+	}
+	for _, s := range []string{`<w:lvl w:ilvl="0"><w:start w:val="1"></w:start><w:numFmt w:val="decimal"></w:numFmt><w:lvlText w:val="%1."></w:lvlText>` +
+		`<w:lvlJc w:val="left"></w:lvlJc><w:pPr><w:ind w:left="720" w:hanging="360"></w:ind></w:pPr></w:lvl>`, `<w:lvlText w:val="•"></w:lvlText>`,
+		`<w:num w:numId="2"><w:abstractNumId w:val="1"></w:abstractNumId></w:num>`} {
+		if !strings.Contains(parts["word/numbering.xml"], s) {
+			t.Error("expected numbering to contain", s)
+		}
+	}
+	if s := `<w:footnote w:id="1">`; !strings.Contains(parts["word/footnotes.xml"], s) || !strings.Contains(parts["word/footnotes.xml"], ">Here is my footnote<") {
+		t.Error("expected footnote", parts["word/footnotes.xml"])
+	}
+}
+
+// an inline PNG and a DIB positioned like its shape, which is written as a BMP
+func TestWriteDOCXPictures(t *testing.T) {
+	pngData := testPNG(3, 2)
+	dib := make([]byte, 48)
+	binary.LittleEndian.PutUint32(dib, 40)
+	binary.LittleEndian.PutUint16(dib[14:], 24)
+	d := &Document{
+		Stories: []Story{{Type: StoryMain, Length: 10, Sections: []Section{{Length: 10, Blocks: []Block{
+			{Paragraph: &Paragraph{Runs: []Run{{Text: "Pictures"}}}}}}}}},
+		Pictures: []Picture{{CP: 2, Format: PicturePNG, Width: 1440, Height: 720, Description: "A grey picture", Data: pngData},
+			{CP: 3, Format: PicturePICT, Data: []byte{1}}, {CP: 4, Format: PictureDIB, ShapeID: 1025, Data: dib},
+			{CP: 20, Format: PicturePNG, Data: pngData}},
+		Shapes: []Shape{{CP: 4, ID: 1025, Left: 100, Top: 200, Width: 2880, Height: 1440, HorizontalOrigin: ShapeOriginParagraph,
+			VerticalOrigin: ShapeOriginPage, Wrap: ShapeWrapTopBottom, BehindText: true}},
+	}
+	var buf bytes.Buffer
+	if err := d.WriteDOCX(&buf); err != nil {
+		t.Fatal("expected to write docx", err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("expected a zip file", err)
+	}
+	parts := map[string][]byte{}
+	for _, file := range z.File {
+		r, _ := file.Open()
+		parts[file.Name], _ = ioutil.ReadAll(r)
+		r.Close()
+	}
+
+	if !bytes.Equal(parts["word/media/image1.png"], pngData) {
+		t.Error("expected the PNG media", parts["word/media/image1.png"])
+	}
+	if b := parts["word/media/image2.bmp"]; len(b) != 14+len(dib) || string(b[:2]) != "BM" || binary.LittleEndian.Uint32(b[10:]) != 54 {
+		t.Error("expected the DIB as a bitmap file", b)
+	}
+	if _, ok := parts["word/media/image3.png"]; ok || len(z.File) != 11 {
+		t.Error("expected the PICT and the picture outside the main story to be left out", len(z.File))
+	}
+	for name, s := range map[string]string{
+		"[Content_Types].xml":          `<Default Extension="png" ContentType="image/png"></Default><Default Extension="bmp" ContentType="image/bmp"></Default>`,
+		"word/_rels/document.xml.rels": `<Relationship Id="rId6" Type="` + relTypeBase + `image" Target="media/image1.png"></Relationship>`,
+		"word/document.xml": `<w:t xml:space="preserve">Pictures</w:t></w:r><w:r><w:drawing><wp:inline><wp:extent cx="914400" cy="457200"></wp:extent>` +
+			`<wp:docPr id="1" name="Picture 1" descr="A grey picture"></wp:docPr>`,
+	} {
+		if !strings.Contains(string(parts[name]), s) {
+			t.Error("expected part to contain", name, s)
+		}
+	}
+	for _, s := range []string{`<a:blip r:embed="rId6"></a:blip>`, `<a:blip r:embed="rId7"></a:blip>`,
+		`<wp:anchor simplePos="0" relativeHeight="3" behindDoc="1" locked="0" layoutInCell="1" allowOverlap="1">`,
+		`<wp:positionH relativeFrom="column"><wp:posOffset>63500</wp:posOffset></wp:positionH><wp:positionV relativeFrom="page"><wp:posOffset>127000</wp:posOffset></wp:positionV>` +
+			`<wp:extent cx="1828800" cy="914400"></wp:extent><wp:wrapTopAndBottom></wp:wrapTopAndBottom>`} {
+		if !strings.Contains(string(parts["word/document.xml"]), s) {
+			t.Error("expected document to contain", s)
+		}
+	}
+}
+
+func TestDocxRunContent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).Encode(docxRun{Content: docxRunContent("a\tb\nc\fd <e>", true)}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<w:r><w:delText xml:space="preserve">a</w:delText><w:tab></w:tab><w:delText xml:space="preserve">b</w:delText><w:br></w:br>` +
+		`<w:delText xml:space="preserve">c</w:delText><w:br w:type="page"></w:br><w:delText xml:space="preserve">d &lt;e&gt;</w:delText></w:r>`
+	if buf.String() != expected {
+		t.Error("expected run content", buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package doc2txt
+
+import "github.com/richardlehane/mscfb"
+
+// sizes of the parts of the Dop that the settings are read from (section 2.7.1)
+const (
+	cbDopBase = 84  // DopBase (section 2.7.2)
+	cbDop97   = 500 // DopBase followed by the rest of Dop97 (section 2.7.4)
+	cbDop2003 = 600 // Dop97, Dop2000 and Dop2002, followed by the part of Dop2003 with iDocProtCur
+)
+
+// the protection types of iDocProtCur in Dop2003 (section 2.7.7)
+var docProtTypes = map[int]string{
+	0: ProtectionTrackChanges,
+	1: ProtectionComments,
+	2: ProtectionForms,
+	3: ProtectionReadOnly,
+}
+
+// read the document settings from the Dop (section 2.7.1). Documents whose Dop is too small to
+// have the DopBase return nil
+func getSettings(table *mscfb.File, fib *fib) (*Settings, error) {
+	b, err := readBlock(table, fib.fibRgFcLcb.fcDop, fib.fibRgFcLcb.lcbDop)
+	if err != nil {
+		return nil, err
+	}
+	return parseDop(b), nil
+}
+
+// parse the settings from a Dop, reading only the parts of it that are present
+func parseDop(b []byte) *Settings {
+	if len(b) < cbDopBase {
+		return nil
+	}
+	fRevMarking := b[5]&0x80 != 0
+	fLockAtn := b[6]&0x10 != 0
+	fProtEnabled := b[7]&0x02 != 0
+	fLockRev := b[7]&0x40 != 0
+
+	s := &Settings{
+		Protection:      ProtectionNone,
+		TrackChanges:    fRevMarking,
+		DefaultTabWidth: getInt16(b, 10),
+		Created:         parseDTTM(uint32(getInt(b, 20))),
+		Revised:         parseDTTM(uint32(getInt(b, 24))),
+		Printed:         parseDTTM(uint32(getInt(b, 28))),
+		Revisions:       getInt16(b, 32),
+		EditingMinutes:  getInt(b, 34),
+		Words:           getInt(b, 38),
+		Characters:      getInt(b, 42),
+		Pages:           getInt16(b, 46),
+		Paragraphs:      getInt(b, 48),
+		Lines:           getInt(b, 56),
+	}
+
+	// the protection in Dop2003 takes precedence, as it also records read-only protection,
+	// otherwise it is given by the flags in the DopBase
+	switch {
+	case len(b) >= cbDop2003 && b[598]&0x08 != 0: // fEnforceDocProt
+		if prot, ok := docProtTypes[int(b[598]>>4&0x07)]; ok {
+			s.Protection = prot
+		}
+	case fLockRev:
+		s.Protection = ProtectionTrackChanges
+	case fLockAtn && len(b) >= cbDop2003 && b[594]&0x01 != 0: // fTreatLockAtnAsReadOnly
+		s.Protection = ProtectionReadOnly
+	case fLockAtn:
+		s.Protection = ProtectionComments
+	case fProtEnabled:
+		s.Protection = ProtectionForms
+	}
+
+	if len(b) >= cbDop97 {
+		s.CharactersAndSpaces = getInt(b, 426)
+		s.FootnoteNumbering = nfcNames[getInt16(b, 492)]
+		s.EndnoteNumbering = nfcNames[getInt16(b, 494)]
+	}
+	return s
+}
@@ -0,0 +1,55 @@
+package doc2txt
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetSettings(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	m, err := ParseMetadata(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+	s := m.Settings
+	if s == nil || s.Protection != ProtectionNone || s.TrackChanges || s.DefaultTabWidth != 720 ||
+		s.FootnoteNumbering != "decimal" || s.EndnoteNumbering != "lowerRoman" {
+		t.Fatal("expected document settings", s)
+	}
+	if s.Created == nil || !s.Created.Equal(time.Date(2017, 8, 7, 16, 17, 0, 0, time.UTC)) || s.Revisions != 2 ||
+		s.Words != 105 || s.Characters != 603 || s.CharactersAndSpaces != 707 || s.Pages != 2 || s.Lines != 5 {
+		t.Error("expected document statistics", s)
+	}
+}
+
+func TestParseDopProtection(t *testing.T) {
+	dop := func(size int, set map[int]byte) []byte {
+		b := make([]byte, size)
+		for i, v := range set {
+			b[i] = v
+		}
+		return b
+	}
+	tests := []struct {
+		dop      []byte
+		expected string
+	}{
+		{dop(cbDopBase, nil), ProtectionNone},
+		{dop(cbDopBase, map[int]byte{7: 0x40}), ProtectionTrackChanges},      // fLockRev
+		{dop(cbDopBase, map[int]byte{6: 0x10}), ProtectionComments},          // fLockAtn
+		{dop(cbDopBase, map[int]byte{7: 0x02}), ProtectionForms},             // fProtEnabled
+		{dop(cbDop2003, map[int]byte{6: 0x10, 594: 1}), ProtectionReadOnly},  // fTreatLockAtnAsReadOnly
+		{dop(cbDop2003, map[int]byte{598: 0x08 | 3<<4}), ProtectionReadOnly}, // fEnforceDocProt
+		{dop(cbDop2003, map[int]byte{7: 0x02, 598: 0x08 | 7<<4}), ProtectionNone},
+		{dop(cbDop2003, map[int]byte{7: 0x02, 598: 7 << 4}), ProtectionForms}, // not enforced
+	}
+	for i, test := range tests {
+		if s := parseDop(test.dop); s == nil || s.Protection != test.expected {
+			t.Errorf("%d: expected %s protection, got %v", i, test.expected, s)
+		}
+	}
+	if parseDop(make([]byte, cbDopBase-1)) != nil {
+		t.Error("expected no settings from a truncated Dop")
+	}
+}
@@ -0,0 +1,235 @@
+package doc2txt
+
+import (
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+const (
+	cbSpa = 26 // the size of an Spa in a PlcfSpa (section 2.8.27)
+
+	pidGtextUNICODE  = 0x00C0 // the text of WordArt
+	pidPib           = 0x0104 // the 1-based index of the BLIP of a picture in the BLIP store
+	pidWzName        = 0x0380 // the name of a shape
+	pidWzDescription = 0x0381 // the alternative text of a shape
+)
+
+// the origins of the horizontal (bx) and vertical (by) positions of a shape in an Spa
+var (
+	spaHorizontalOrigins = map[int]string{0: ShapeOriginMargin, 1: ShapeOriginPage, 2: ShapeOriginColumn}
+	spaVerticalOrigins   = map[int]string{0: ShapeOriginMargin, 1: ShapeOriginPage, 2: ShapeOriginParagraph}
+	spaWraps             = map[int]string{0: ShapeWrapAround, 1: ShapeWrapTopBottom, 2: ShapeWrapSquare, 3: ShapeWrapNone,
+		4: ShapeWrapTight, 5: ShapeWrapThrough}
+)
+
+// officeArtContent is the drawing data of the document from the OfficeArtContent at fcDggInfo
+// (section 2.9.171): the BLIP store with the pictures used by shapes, and the shapes of the
+// drawings in the main document and the headers by shape ID
+type officeArtContent struct {
+	bstore []officeArtRecord // the OfficeArtFBSE records of the OfficeArtBStoreContainer
+	shapes map[int]officeArtShape
+}
+
+// read the OfficeArtContent from the table stream. Documents without drawings return nil
+func getOfficeArtContent(table *mscfb.File, fib *fib) (*officeArtContent, error) {
+	if fib.fibRgFcLcb.lcbDggInfo == 0 {
+		return nil, nil
+	}
+	b, err := readBlock(table, fib.fibRgFcLcb.fcDggInfo, fib.fibRgFcLcb.lcbDggInfo)
+	if err != nil {
+		return nil, err
+	}
+	return parseOfficeArtContent(b)
+}
+
+// parse an OfficeArtContent, which is an OfficeArtDggContainer followed by an
+// OfficeArtWordDrawing for each of the main document and the headers. Each OfficeArtWordDrawing
+// is a dgglbl byte followed by an OfficeArtDgContainer
+func parseOfficeArtContent(b []byte) (*officeArtContent, error) {
+	dgg, offset, err := parseOfficeArtRecord(b)
+	if err != nil {
+		return nil, err
+	}
+	if dgg.recType != rtDggContainer {
+		return nil, errInvalidOfficeArt
+	}
+	c := &officeArtContent{shapes: map[int]officeArtShape{}}
+
+	children, err := parseOfficeArtRecords(dgg.data)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if child.recType != rtBStoreContainer {
+			continue
+		}
+		if c.bstore, err = parseOfficeArtRecords(child.data); err != nil {
+			return nil, err
+		}
+	}
+
+	for offset+1 < len(b) {
+		dg, n, err := parseOfficeArtRecord(b[offset+1:]) // skip the dgglbl
+		if err != nil {
+			return nil, err
+		}
+		offset += 1 + n
+		if dg.recType != rtDgContainer {
+			return nil, errInvalidOfficeArt
+		}
+		shapes, err := findOfficeArtShapes([]officeArtRecord{dg})
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range shapes {
+			c.shapes[s.spid] = s
+		}
+	}
+	return c, nil
+}
+
+// the BLIP of the picture of a shape from the BLIP store, or nil if the shape has no picture
+func (c *officeArtContent) shapeBlip(s officeArtShape, delay *mscfb.File) ([]byte, error) {
+	pib, ok := s.props[pidPib]
+	if !ok || pib.value < 1 || pib.value > len(c.bstore) {
+		return nil, nil
+	}
+	fbse := c.bstore[pib.value-1]
+	if fbse.recType != rtFBSE {
+		return nil, nil
+	}
+	return fbse.blip(delay)
+}
+
+// spa is the anchor of a shape from a PlcfSpa (section 2.9.253)
+type spa struct {
+	cp        int
+	lid       int // the shape ID
+	left      int // the bounding rectangle in twips, relative to the origins
+	top       int
+	right     int
+	bottom    int
+	bx        int
+	by        int
+	wr        int
+	belowText bool // only used when wr is 3, where the shape has no text wrapping
+}
+
+// read the anchors of the main document and the headers
+func (w *wordFile) spas() ([]spa, error) {
+	fc := w.fib.fibRgFcLcb
+	main, err := getSpas(w.table, fc.fcPlcSpaMom, fc.lcbPlcSpaMom, 0)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := getSpas(w.table, fc.fcPlcSpaHdr, fc.lcbPlcSpaHdr, w.fib.fibRgLw.ccpText+w.fib.fibRgLw.ccpFtn)
+	if err != nil {
+		return nil, err
+	}
+	return append(main, headers...), nil
+}
+
+// read the shape anchors of the main document (PlcSpaMom) or the headers (PlcSpaHdr), whose CPs
+// are offset by cpOffset to make them CPs in the document as a whole
+func getSpas(table *mscfb.File, fc, lcb, cpOffset int) ([]spa, error) {
+	p, err := getPlc(table, fc, lcb, cbSpa)
+	if err != nil {
+		return nil, err
+	}
+	spas := make([]spa, len(p.aData))
+	for i, b := range p.aData {
+		flags := getInt16(b, 20)
+		spas[i] = spa{
+			cp:        p.aCP[i] + cpOffset,
+			lid:       getInt(b, 0),
+			left:      int(int32(getInt(b, 4))),
+			top:       int(int32(getInt(b, 8))),
+			right:     int(int32(getInt(b, 12))),
+			bottom:    int(int32(getInt(b, 16))),
+			bx:        flags >> 1 & 0x03,
+			by:        flags >> 3 & 0x03,
+			wr:        flags >> 5 & 0x0F,
+			belowText: flags&0x4000 != 0,
+		}
+	}
+	return spas, nil
+}
+
+// add the floating shapes of the main document and the headers, with their pictures
+func (b *docBuilder) shapes() error {
+	content, err := getOfficeArtContent(b.w.table, b.w.fib)
+	if err != nil || content == nil {
+		return err
+	}
+	spas, err := b.w.spas()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range spas {
+		s := Shape{
+			CP:               a.cp,
+			ID:               a.lid,
+			Left:             a.left,
+			Top:              a.top,
+			Width:            a.right - a.left,
+			Height:           a.bottom - a.top,
+			HorizontalOrigin: spaHorizontalOrigins[a.bx],
+			VerticalOrigin:   spaVerticalOrigins[a.by],
+			Wrap:             spaWraps[a.wr],
+			BehindText:       a.wr == 3 && a.belowText,
+		}
+		shape, ok := content.shapes[a.lid]
+		if ok {
+			s.Type = shape.shapeType
+			s.Name = shape.props[pidWzName].stringValue()
+			s.Description = shape.props[pidWzDescription].stringValue()
+			s.Text = shape.props[pidGtextUNICODE].stringValue()
+		}
+		b.doc.Shapes = append(b.doc.Shapes, s)
+		if ok {
+			b.addShapePicture(content, shape, s)
+		}
+	}
+	return nil
+}
+
+// add the picture of a floating shape. Pictures that cannot be read are left out, as they are
+// for inline pictures
+func (b *docBuilder) addShapePicture(content *officeArtContent, shape officeArtShape, s Shape) {
+	blip, err := content.shapeBlip(shape, b.w.wordDoc)
+	if err != nil || blip == nil {
+		return
+	}
+	format, data, err := parseBlip(blip)
+	if err != nil {
+		return
+	}
+	pixelWidth, pixelHeight := pixelSize(format, data)
+	b.doc.Pictures = append(b.doc.Pictures, Picture{CP: s.CP, ShapeID: s.ID, Format: format, Width: s.Width,
+		Height: s.Height, PixelWidth: pixelWidth, PixelHeight: pixelHeight, Name: s.Name, Description: s.Description,
+		Data: data})
+}
+
+// find the text of the WordArt shapes by the CP of their anchors, so that it can be written in
+// place of the anchor. The text is followed by a paragraph mark as WordArt is usually a heading
+// or a line of its own. The text is still readable without it, so drawings that cannot be read
+// are ignored
+func getWordArt(w *wordFile) map[int][]uint16 {
+	content, err := getOfficeArtContent(w.table, w.fib)
+	if err != nil || content == nil {
+		return nil
+	}
+	spas, err := w.spas()
+	if err != nil {
+		return nil
+	}
+	wordArt := map[int][]uint16{}
+	for _, a := range spas {
+		if text := content.shapes[a.lid].props[pidGtextUNICODE].stringValue(); text != "" {
+			wordArt[a.cp] = utf16.Encode([]rune(text + "\r"))
+		}
+	}
+	return wordArt
+}
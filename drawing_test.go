@@ -0,0 +1,167 @@
+package doc2txt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// make an OfficeArtFBSE record with the BLIP embedded in it
+func fbseTestRecord(blip []byte) []byte {
+	data := make([]byte, cbFBSE)
+	binary.LittleEndian.PutUint32(data[20:], uint32(len(blip)))
+	binary.LittleEndian.PutUint32(data[24:], 1) // cRef
+	return officeArtTestRecord(2, 0, rtFBSE, append(data, blip...))
+}
+
+// a string property for foptTestRecord
+type testStringProperty struct {
+	pid  int
+	text string
+}
+
+// make an OfficeArtFOPT with a pib, unless it is zero, and string properties
+func foptTestRecord(pib int, props ...testStringProperty) []byte {
+	var data, complexData []byte
+	if pib != 0 {
+		data = make([]byte, 6)
+		binary.LittleEndian.PutUint16(data, pidPib|0x4000) // fBid
+		binary.LittleEndian.PutUint32(data[2:], uint32(pib))
+	}
+	for _, p := range props {
+		start := len(complexData)
+		for _, u := range append(utf16.Encode([]rune(p.text)), 0) {
+			complexData = append(complexData, byte(u), byte(u>>8))
+		}
+		opid := make([]byte, 6)
+		binary.LittleEndian.PutUint16(opid, uint16(p.pid|0x8000)) // fComplex
+		binary.LittleEndian.PutUint32(opid[2:], uint32(len(complexData)-start))
+		data = append(data, opid...)
+	}
+	return officeArtTestRecord(3, len(data)/6, rtFOPT, append(data, complexData...))
+}
+
+// make an OfficeArtSpContainer with an OfficeArtFSP and the properties
+func spContainerTestRecord(spid, shapeType int, fopt []byte) []byte {
+	fsp := make([]byte, 8)
+	binary.LittleEndian.PutUint32(fsp, uint32(spid))
+	data := append(officeArtTestRecord(2, shapeType, rtFSP, fsp), fopt...)
+	return officeArtTestRecord(recVerContainer, 0, rtSpContainer, data)
+}
+
+func TestParseOfficeArtContent(t *testing.T) {
+	pngData := testPNG(3, 2)
+	bstore := officeArtTestRecord(recVerContainer, 1, rtBStoreContainer,
+		fbseTestRecord(bitmapTestBlip(rtBlipPNG, 0x6E0, false, pngData)))
+	b := officeArtTestRecord(recVerContainer, 0, rtDggContainer, bstore)
+
+	group := officeArtTestRecord(recVerContainer, 0, 0xF003, // OfficeArtSpgrContainer
+		append(spContainerTestRecord(0x400, 0, nil), spContainerTestRecord(0x401, 75,
+			foptTestRecord(1, testStringProperty{pidWzName, "Picture 1"}, testStringProperty{pidWzDescription, "A grey picture"}))...))
+	b = append(b, 0) // dgglbl for the main document
+	b = append(b, officeArtTestRecord(recVerContainer, 0, rtDgContainer, group)...)
+
+	c, err := parseOfficeArtContent(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.bstore) != 1 || len(c.shapes) != 2 {
+		t.Fatal("expected one BLIP and two shapes", c)
+	}
+	s := c.shapes[0x401]
+	if s.shapeType != 75 || s.props[pidWzName].stringValue() != "Picture 1" ||
+		s.props[pidWzDescription].stringValue() != "A grey picture" {
+		t.Error("expected picture frame with a name and description", s)
+	}
+	blip, err := c.shapeBlip(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format, data, err := parseBlip(blip); err != nil || format != PicturePNG || !bytes.Equal(data, pngData) {
+		t.Error("expected PNG BLIP", format, err)
+	}
+	if blip, err := c.shapeBlip(c.shapes[0x400], nil); blip != nil || err != nil {
+		t.Error("expected no BLIP for a shape without a pib", blip, err)
+	}
+
+	if _, err := parseOfficeArtContent(b[:len(b)-4]); err != errInvalidOfficeArt {
+		t.Error("expected truncated drawing to fail", err)
+	}
+}
+
+func TestShapes(t *testing.T) {
+	f, err := os.Open(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := openWordFile(f, &options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := getDocument(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the text box in the sample document
+	expected := []Shape{{CP: 0xF2, ID: 0x403, Type: 202, Left: -154, Top: 117, Width: 3974, Height: 434,
+		HorizontalOrigin: ShapeOriginColumn, VerticalOrigin: ShapeOriginParagraph, Wrap: ShapeWrapNone, Name: "Text Box 2"}}
+	if !reflect.DeepEqual(d.Shapes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, d.Shapes)
+	}
+	if len(d.Pictures) != 0 {
+		t.Error("expected no pictures for the text box", d.Pictures)
+	}
+}
+
+func TestWordArt(t *testing.T) {
+	b, err := ioutil.ReadFile(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := &memoryFile{b: b}
+	doc, _ := mscfb.New(mem)
+	wordDoc, table0, table1 := getWordDocAndTables(doc)
+	fib, err := getFib(wordDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := getActiveTable(wordDoc, table0, table1, fib)
+
+	// replace the text box in the sample document with WordArt, keeping its shape ID and anchor
+	fopt := foptTestRecord(0, testStringProperty{pidGtextUNICODE, "Heading"})
+	content := officeArtTestRecord(recVerContainer, 0, rtDggContainer, nil)
+	content = append(content, 0)
+	content = append(content, officeArtTestRecord(recVerContainer, 0, rtDgContainer, spContainerTestRecord(0x403, 136, fopt))...)
+	table.WriteAt(content, int64(fib.fibRgFcLcb.fcDggInfo))
+
+	w, err := openWordFile(bytes.NewReader(mem.b), &options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.fib.fibRgFcLcb.lcbDggInfo = len(content)
+
+	d, err := getDocument(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Shapes) != 1 || d.Shapes[0].Type != 136 || d.Shapes[0].Text != "Heading" {
+		t.Fatal("expected WordArt shape", d.Shapes)
+	}
+	r, err := getText(w, getOptions(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := ioutil.ReadAll(r)
+	if !strings.Contains(string(text), "Heading\r") {
+		t.Errorf("expected WordArt text in %q", text)
+	}
+}
@@ -0,0 +1,60 @@
+package doc2txt
+
+import (
+	"errors"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// ErrEncrypted is returned when a document is password protected. The error returned
+// is an *EncryptedError, which gives the kind of encryption
+var ErrEncrypted = errors.New("document is encrypted")
+
+// EncryptionKind is the mechanism used to password protect a document (section 2.2.6)
+type EncryptionKind string
+
+// The kinds of password protection
+const (
+	EncryptionXOR          EncryptionKind = "xor"
+	EncryptionRC4          EncryptionKind = "rc4"
+	EncryptionRC4CryptoAPI EncryptionKind = "rc4CryptoAPI"
+	EncryptionUnknown      EncryptionKind = "unknown"
+)
+
+// EncryptedError reports that a document is password protected. It matches ErrEncrypted with errors.Is
+type EncryptedError struct {
+	Kind EncryptionKind
+}
+
+func (e *EncryptedError) Error() string {
+	return ErrEncrypted.Error() + " (" + string(e.Kind) + ")"
+}
+
+// Is reports whether target is ErrEncrypted
+func (e *EncryptedError) Is(target error) bool {
+	return target == ErrEncrypted
+}
+
+// find the kind of encryption. XOR obfuscation is flagged in the FibBase, and the RC4 kinds are
+// given by the version of the EncryptionHeader in the first lKey bytes of the table stream (section 2.2.6)
+func getEncryptionError(fib *fib, table *mscfb.File) error {
+	if fib.base.fObfuscated || fib.isWord6() { // Word 6.0 and Word 95 only have XOR obfuscation
+		return &EncryptedError{Kind: EncryptionXOR}
+	}
+	b := make([]byte, 4)
+	if _, err := table.ReadAt(b, 0); err != nil {
+		return &EncryptedError{Kind: EncryptionUnknown}
+	}
+	return &EncryptedError{Kind: encryptionKind(getInt16(b, 0), getInt16(b, 2))}
+}
+
+// the kind of RC4 encryption from the EncryptionVersionInfo ([MS-OFFCRYPTO] sections 2.3.6.1 and 2.3.5.1)
+func encryptionKind(vMajor, vMinor int) EncryptionKind {
+	switch {
+	case vMajor == 1 && vMinor == 1:
+		return EncryptionRC4
+	case (vMajor == 2 || vMajor == 3 || vMajor == 4) && vMinor == 2:
+		return EncryptionRC4CryptoAPI
+	}
+	return EncryptionUnknown
+}
@@ -0,0 +1,30 @@
+package doc2txt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseEncrypted(t *testing.T) {
+	tests := []struct {
+		name string
+		kind EncryptionKind
+	}{
+		{"rc4", EncryptionRC4},
+		{"rc4CryptoAPI", EncryptionRC4CryptoAPI},
+		{"unknownEncryption", EncryptionUnknown},
+	}
+	for _, test := range tests {
+		_, err := ParseDoc(bytes.NewReader(encryptedTestDoc(t, test.name)))
+		var encrypted *EncryptedError
+		if !errors.Is(err, ErrEncrypted) || !errors.As(err, &encrypted) || encrypted.Kind != test.kind {
+			t.Error("expected encrypted error", test.kind, err)
+		}
+	}
+
+	_, err := ParseDocument(bytes.NewReader(encryptedTestDoc(t, "rc4")))
+	if !errors.Is(err, ErrEncrypted) || err.Error() != "Error processing file: document is encrypted (rc4)" {
+		t.Error("expected encrypted error from ParseDocument", err)
+	}
+}
@@ -0,0 +1,236 @@
+package doc2txt
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// convert the instruction of an EQ field, such as EQ \f(1,2), to LaTeX. The switches of the
+// field are single letters that can be followed by options and then their arguments in
+// parentheses, separated by commas or semicolons
+func eqFieldToLaTeX(instruction string) string {
+	s := strings.TrimSpace(instruction)
+	if len(s) >= 2 && strings.EqualFold(s[:2], "EQ") {
+		s = s[2:]
+	}
+	p := &eqParser{s: []rune(s)}
+	return strings.TrimSpace(p.sequence(""))
+}
+
+// the text written for the instruction of a field without a result, which is the LaTeX of an EQ
+// field between dollar signs and nothing for other fields
+func fieldInstructionText(instruction []uint16) []uint16 {
+	s := string(utf16.Decode(instruction))
+	if words := strings.Fields(s); len(words) == 0 || !strings.EqualFold(words[0], "EQ") {
+		return nil
+	}
+	if latex := eqFieldToLaTeX(s); latex != "" {
+		return utf16.Encode([]rune("$" + latex + "$"))
+	}
+	return nil
+}
+
+// eqParser reads the switches and text of an EQ field instruction
+type eqParser struct {
+	s   []rune
+	pos int
+}
+
+// read switches and text up to one of the stop characters, returning the LaTeX
+func (p *eqParser) sequence(stops string) string {
+	var parts []string
+	for p.pos < len(p.s) {
+		r := p.s[p.pos]
+		switch {
+		case strings.ContainsRune(stops, r):
+			return latexJoin(parts...)
+		case r == '\\':
+			parts = append(parts, p.backslash())
+		case r == '(': // parentheses that are part of the text
+			p.pos++
+			inner := p.sequence(")")
+			p.pos++
+			parts = append(parts, "(", inner, ")")
+		default:
+			p.pos++
+			parts = append(parts, latexChar(r))
+		}
+	}
+	return latexJoin(parts...)
+}
+
+// read a switch with its options and arguments, or a character escaped with a backslash
+func (p *eqParser) backslash() string {
+	p.pos++
+	if p.pos >= len(p.s) {
+		return latexChar('\\')
+	}
+	r := p.s[p.pos]
+	p.pos++
+	if !unicode.IsLetter(r) {
+		return latexChar(r)
+	}
+	name := unicode.ToLower(r)
+	options := p.options()
+	args := p.arguments()
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	switch name {
+	case 'a': // array
+		columns, _ := strconv.Atoi(options["co"])
+		return latexMatrix(args, columns)
+	case 'b': // brackets
+		left, right := "(", ")"
+		if c, ok := options["bc"]; ok {
+			left, right = eqBracket(c, 0), eqBracket(c, 1)
+		}
+		if c, ok := options["lc"]; ok {
+			left = eqBracket(c, 0)
+		}
+		if c, ok := options["rc"]; ok {
+			right = eqBracket(c, 1)
+		}
+		return latexFence(left, right, strings.Join(args, ","))
+	case 'd': // displacement, which only moves what follows
+		return ""
+	case 'f': // fraction
+		return `\frac{` + arg(0) + "}{" + arg(1) + "}"
+	case 'i': // integral, or a sum or product, with its limits
+		op := `\int`
+		if _, ok := options["su"]; ok {
+			op = `\sum`
+		} else if _, ok := options["pr"]; ok {
+			op = `\prod`
+		} else if c, ok := options["fc"]; ok {
+			op = latexText(c)
+		} else if c, ok := options["vc"]; ok {
+			op = latexText(c)
+		}
+		return latexJoin(latexLimits(op, arg(0), arg(1)), " ", arg(2))
+	case 'l': // list
+		return strings.Join(args, ",")
+	case 'o': // overstrike
+		return latexJoin(args...)
+	case 'r': // radical
+		if len(args) > 1 {
+			return `\sqrt[` + arg(0) + "]{" + arg(1) + "}"
+		}
+		return `\sqrt{` + arg(0) + "}"
+	case 's': // superscript, subscript or elements stacked vertically
+		if _, ok := options["up"]; ok {
+			return "^{" + strings.Join(args, "") + "}"
+		}
+		if _, ok := options["do"]; ok {
+			return "_{" + strings.Join(args, "") + "}"
+		}
+		if len(args) == 1 {
+			return args[0]
+		}
+		return latexMatrix(args, 1)
+	case 'x': // box
+		return `\boxed{` + strings.Join(args, "") + "}"
+	}
+	return latexJoin(args...)
+}
+
+// read the options of a switch, such as \up8 or \lc\{, by name. The bracket and character options
+// have a character as their value, and the others an optional number
+func (p *eqParser) options() map[string]string {
+	options := map[string]string{}
+	for {
+		p.skipSpace()
+		if p.pos+2 >= len(p.s) || p.s[p.pos] != '\\' || !unicode.IsLetter(p.s[p.pos+1]) || !unicode.IsLetter(p.s[p.pos+2]) {
+			return options
+		}
+		name := strings.ToLower(string(p.s[p.pos+1 : p.pos+3]))
+		p.pos += 3
+		switch name {
+		case "lc", "rc", "bc", "fc", "vc":
+			if p.pos < len(p.s) && p.s[p.pos] == '\\' {
+				p.pos++
+			}
+			if p.pos < len(p.s) {
+				options[name] = string(p.s[p.pos])
+				p.pos++
+			}
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && unicode.IsDigit(p.s[p.pos]) {
+				p.pos++
+			}
+			options[name] = string(p.s[start:p.pos])
+		}
+	}
+}
+
+// read the arguments in parentheses after a switch, if there are any
+func (p *eqParser) arguments() []string {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != '(' {
+		return nil
+	}
+	p.pos++
+	var args []string
+	for p.pos < len(p.s) {
+		args = append(args, strings.TrimSpace(p.sequence(",;)")))
+		if p.pos >= len(p.s) {
+			break
+		}
+		p.pos++
+		if p.s[p.pos-1] == ')' {
+			break
+		}
+	}
+	return args
+}
+
+func (p *eqParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// the LaTeX for the left (side 0) or right (side 1) of a bracket given by an EQ field option
+func eqBracket(c string, side int) string {
+	for _, r := range c {
+		if b, ok := latexBrackets[r]; ok {
+			return b[side]
+		}
+		return latexChar(r)
+	}
+	return ""
+}
+
+// write an operator such as \int with its lower and upper limits
+func latexLimits(op, lower, upper string) string {
+	if lower != "" {
+		op += "_{" + lower + "}"
+	}
+	if upper != "" {
+		op += "^{" + upper + "}"
+	}
+	return op
+}
+
+// write elements in rows of the given number of columns
+func latexMatrix(elements []string, columns int) string {
+	if columns < 1 {
+		columns = 1
+	}
+	var rows []string
+	for i := 0; i < len(elements); i += columns {
+		end := i + columns
+		if end > len(elements) {
+			end = len(elements)
+		}
+		rows = append(rows, strings.Join(elements[i:end], " & "))
+	}
+	return `\begin{matrix} ` + strings.Join(rows, ` \\ `) + ` \end{matrix}`
+}
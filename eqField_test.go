@@ -0,0 +1,53 @@
+package doc2txt
+
+import (
+	"bytes"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestEqFieldToLaTeX(t *testing.T) {
+	for _, test := range []struct {
+		instruction, latex string
+	}{
+		{`EQ \f(1,2)`, `\frac{1}{2}`},
+		{`eq \F(a+b;c)`, `\frac{a+b}{c}`},
+		{`EQ \r(x)`, `\sqrt{x}`},
+		{`EQ \r(3,x)`, `\sqrt[3]{x}`},
+		{`EQ x\s\up8(2)\s\do4(i)`, `x^{2}_{i}`},
+		{`EQ \b\lc\{(\a\co2(1,2,3,4))`, `\left\{ \begin{matrix} 1 & 2 \\ 3 & 4 \end{matrix} \right)`},
+		{`EQ \b\bc\[(x)`, `\left[ x \right]`},
+		{`EQ \i\su(i=1,n,i)`, `\sum_{i=1}^{n} i`},
+		{`EQ \i(0,1,x dx)`, `\int_{0}^{1} x dx`},
+		{`EQ \x(α)\l(a,b)`, `\boxed{\alpha}a,b`},
+		{`EQ \o(=,/)`, `=/`},
+		{`EQ \d\fo10()f(x\,y)`, `f(x,y)`},
+		{`EQ \s(a,b)`, `\begin{matrix} a \\ b \end{matrix}`},
+		{`EQ \f(\r(2),2)`, `\frac{\sqrt{2}}{2}`},
+	} {
+		if latex := eqFieldToLaTeX(test.instruction); latex != test.latex {
+			t.Errorf("%s: expected %s, got %s", test.instruction, test.latex, latex)
+		}
+	}
+}
+
+func TestEqFieldText(t *testing.T) {
+	chars := utf16.Encode([]rune("a \x13 EQ \\f(1,2) \x15 b \x13 PAGE \x142\x15"))
+	var buf bytes.Buffer
+	translateText(chars, nil, nil, &buf)
+	if s := buf.String(); s != `a $\frac{1}{2}$ b 2` {
+		t.Errorf("expected the equation as LaTeX, got %q", s)
+	}
+}
+
+func TestEqFieldEquation(t *testing.T) {
+	b := &docBuilder{doc: &Document{}}
+	for cp, c := range utf16.Encode([]rune("\x13EQ \\r(x)\x15")) {
+		if !b.fieldCharacter(c, cp, 0, StoryMain, nil) {
+			b.addFieldText(c)
+		}
+	}
+	if len(b.doc.Fields) != 1 || b.doc.Fields[0].Type != "EQ" || b.doc.Fields[0].Equation != `\sqrt{x}` {
+		t.Error("expected the equation of the EQ field", b.doc.Fields)
+	}
+}
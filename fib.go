@@ -19,10 +19,20 @@ type fib struct {
 	fibRgLw    fibRgLw
 	cbRgFcLcb  int
 	fibRgFcLcb fibRgFcLcb
+	cswNew     int
+	nFibNew    int
 }
 
 type fibBase struct {
+	nFib         int
+	lid          int
+	fComplex     bool
+	fEncrypted   bool
 	fWhichTblStm int
+	fObfuscated  bool
+	lKey         int
+	fcMin        int // the start and end of the text in files without a piece table (reserved5 and reserved6)
+	fcMac        int
 }
 
 type fibRgW struct {
@@ -41,32 +51,177 @@ type fibRgLw struct {
 }
 
 type fibRgFcLcb struct {
-	fcPlcfFldMom  int
-	lcbPlcfFldMom int
-	fcPlcfFldHdr  int
-	lcbPlcfFldHdr int
-	fcPlcfFldFtn  int
-	lcbPlcfFldFtn int
-	fcPlcfFldAtn  int
-	lcbPlcfFldAtn int
-	fcClx         int
-	lcbClx        int
+	fcStshf            int
+	lcbStshf           int
+	fcPlcffndRef       int
+	lcbPlcffndRef      int
+	fcPlcffndTxt       int
+	lcbPlcffndTxt      int
+	fcPlcfandRef       int
+	lcbPlcfandRef      int
+	fcPlcfandTxt       int
+	lcbPlcfandTxt      int
+	fcPlcfSed          int
+	lcbPlcfSed         int
+	fcPlcfBteChpx      int
+	lcbPlcfBteChpx     int
+	fcPlcfBtePapx      int
+	lcbPlcfBtePapx     int
+	fcPlcfFldMom       int
+	lcbPlcfFldMom      int
+	fcPlcfFldHdr       int
+	lcbPlcfFldHdr      int
+	fcPlcfFldFtn       int
+	lcbPlcfFldFtn      int
+	fcPlcfFldAtn       int
+	lcbPlcfFldAtn      int
+	fcSttbfFfn         int
+	lcbSttbfFfn        int
+	fcDop              int
+	lcbDop             int
+	fcClx              int
+	lcbClx             int
+	fcGrpXstAtnOwners  int
+	lcbGrpXstAtnOwners int
+	fcPlcSpaMom        int
+	lcbPlcSpaMom       int
+	fcPlcSpaHdr        int
+	lcbPlcSpaHdr       int
+	fcPlcfendRef       int
+	lcbPlcfendRef      int
+	fcPlcfendTxt       int
+	lcbPlcfendTxt      int
+	fcPlcfFldEdn       int
+	lcbPlcfFldEdn      int
+	fcDggInfo          int
+	lcbDggInfo         int
+	fcSttbfRMark       int
+	lcbSttbfRMark      int
+	fcPlcfFldTxbx      int
+	lcbPlcfFldTxbx     int
+	fcPlcffldHdrTxbx   int
+	lcbPlcffldHdrTxbx  int
+	fcPlfLst           int
+	lcbPlfLst          int
+	fcPlfLfo           int
+	lcbPlfLfo          int
+
+	// FibRgFcLcb2000 (section 2.5.7)
+	fcPlcfTch       int
+	lcbPlcfTch      int
+	fcRmdThreading  int
+	lcbRmdThreading int
+
+	// FibRgFcLcb2002 (section 2.5.8)
+	fcPlrsid            int
+	lcbPlrsid           int
+	fcSttbfBkmkFactoid  int
+	lcbSttbfBkmkFactoid int
+	fcPlcfBkfFactoid    int
+	lcbPlcfBkfFactoid   int
+	fcPlcfBklFactoid    int
+	lcbPlcfBklFactoid   int
+	fcFactoidData       int
+	lcbFactoidData      int
+	fcPlcffactoid       int
+	lcbPlcffactoid      int
+
+	// FibRgFcLcb2003 (section 2.5.9)
+	fcHplxsdr        int
+	lcbHplxsdr       int
+	fcSttbfBkmkSdt   int
+	lcbSttbfBkmkSdt  int
+	fcPlcfBkfSdt     int
+	lcbPlcfBkfSdt    int
+	fcPlcfBklSdt     int
+	lcbPlcfBklSdt    int
+	fcCustomXForm    int
+	lcbCustomXForm   int
+	fcSttbfBkmkProt  int
+	lcbSttbfBkmkProt int
+	fcPlcfBkfProt    int
+	lcbPlcfBkfProt   int
+	fcPlcfBklProt    int
+	lcbPlcfBklProt   int
+	fcSttbProtUser   int
+	lcbSttbProtUser  int
+
+	// FibRgFcLcb2007 (section 2.5.10)
+	fcPlcfmthd            int
+	lcbPlcfmthd           int
+	fcSttbfBkmkMoveFrom   int
+	lcbSttbfBkmkMoveFrom  int
+	fcPlcfBkfMoveFrom     int
+	lcbPlcfBkfMoveFrom    int
+	fcPlcfBklMoveFrom     int
+	lcbPlcfBklMoveFrom    int
+	fcSttbfBkmkMoveTo     int
+	lcbSttbfBkmkMoveTo    int
+	fcPlcfBkfMoveTo       int
+	lcbPlcfBkfMoveTo      int
+	fcPlcfBklMoveTo       int
+	lcbPlcfBklMoveTo      int
+	fcOssTheme            int
+	lcbOssTheme           int
+	fcColorSchemeMapping  int
+	lcbColorSchemeMapping int
 }
 
+// the number of 64-bit values in each version of FibRgFcLcb (section 2.5.1)
+const (
+	cbRgFcLcb97   = 0x005D
+	cbRgFcLcb2000 = 0x006C
+	cbRgFcLcb2002 = 0x0088
+	cbRgFcLcb2003 = 0x00A4
+	cbRgFcLcb2007 = 0x00B7
+)
+
+// the wIdent at the start of the FIB (section 2.5.2). Word 6.0 and Word 95 use a different value
+const (
+	wIdentWord97 = 0xA5EC
+	wIdentWord6  = 0xA5DC
+)
+
+// the largest FIB that is read: FibBase, csw, FibRgW97, cslw, FibRgLw97, cbRgFcLcb, FibRgFcLcb2007,
+// cswNew and FibRgCswNew with FibRgCswNewData2007
+const fibMaxSize = 32 + 2 + 28 + 2 + 88 + 2 + cbRgFcLcb2007*8 + 2 + 2 + 8
+
 // parse File Information Block (section 2.5.1)
 func getFib(wordDoc *mscfb.File) (*fib, error) {
 	if wordDoc == nil {
 		return nil, errDocEmpty
 	}
 
-	b := make([]byte, 898) // get FIB block up to FibRgFcLcb97
+	size := fibMaxSize
+	if int(wordDoc.Size) < size {
+		size = int(wordDoc.Size)
+	}
+	b := make([]byte, size)
 	_, err := wordDoc.ReadAt(b, 0)
 	if err != nil {
 		return nil, err
 	}
+	if len(b) < 32 {
+		return nil, errFibInvalid
+	}
+	if wIdent := getInt16(b, 0); wIdent != wIdentWord97 && wIdent != wIdentWord6 {
+		return nil, errFibInvalid
+	}
 
 	fibBase := getFibBase(b[0:32])
+	if fibBase.nFib >= nFibWord6 && fibBase.nFib <= nFibWord95 {
+		return getFibWord6(b, fibBase)
+	}
+	f, err := parseFib(b, fibBase)
+	if err != nil && fibBase.fEncrypted {
+		// only the start of the FIB is stored unencrypted, so the rest is read after decryption (section 2.2.6)
+		return &fib{base: *fibBase}, nil
+	}
+	return f, err
+}
 
+// parse the parts of the FIB that follow FibBase (section 2.5.1)
+func parseFib(b []byte, fibBase *fibBase) (*fib, error) {
 	fibRgW, csw, err := getFibRgW(b, 32)
 	if err != nil {
 		return nil, err
@@ -77,16 +232,37 @@ func getFib(wordDoc *mscfb.File) (*fib, error) {
 		return nil, err
 	}
 
-	fibRgFcLcb, cbRgFcLcb, err := getFibRgFcLcb(b, 34+csw+2+cslw)
+	fibRgFcLcbStart := 34 + csw + 2 + cslw
+	fibRgFcLcb, cbRgFcLcb, err := getFibRgFcLcb(b, fibRgFcLcbStart)
+	if err != nil {
+		return nil, err
+	}
+
+	cswNew, nFibNew := getFibRgCswNew(b, fibRgFcLcbStart+2+cbRgFcLcb*8)
+	return &fib{base: *fibBase, csw: csw, cslw: cslw, fibRgW: *fibRgW, fibRgLw: *fibRgLw, fibRgFcLcb: *fibRgFcLcb, cbRgFcLcb: cbRgFcLcb,
+		cswNew: cswNew, nFibNew: nFibNew}, nil
+}
 
-	return &fib{base: *fibBase, csw: csw, cslw: cslw, fibRgW: *fibRgW, fibRgLw: *fibRgLw, fibRgFcLcb: *fibRgFcLcb, cbRgFcLcb: cbRgFcLcb}, err
+// the version of the file format, from FibRgCswNew if it is present (section 2.5.14)
+func (f *fib) getNFib() int {
+	if f.cswNew != 0 {
+		return f.nFibNew
+	}
+	return f.base.nFib
 }
 
 // parse FibBase (section 2.5.2)
 func getFibBase(fib []byte) *fibBase {
+	nFib := getInt16(fib, 2)
+	lid := getInt16(fib, 6)
 	byt := fib[11]                    // fWhichTblStm is 2nd highest bit in this byte
 	fWhichTblStm := int(byt >> 1 & 1) // set which table (0Table or 1Table) is the table stream
-	return &fibBase{fWhichTblStm: fWhichTblStm}
+	fEncrypted := byt&1 == 1
+	fObfuscated := byt>>7 == 1
+	fComplex := fib[10]&0x04 != 0
+	lKey := getInt(fib, 14)
+	return &fibBase{nFib: nFib, lid: lid, fComplex: fComplex, fEncrypted: fEncrypted, fWhichTblStm: fWhichTblStm,
+		fObfuscated: fObfuscated, lKey: lKey, fcMin: getInt(fib, 24), fcMac: getInt(fib, 28)}
 }
 
 func getFibRgW(fib []byte, start int) (*fibRgW, int, error) {
@@ -115,38 +291,110 @@ func getFibRgLw(fib []byte, start int) (*fibRgLw, int, error) {
 	ccpTxbx := getInt(fib, fibRgLwStart+9*4)
 	ccpHdrTxbx := getInt(fib, fibRgLwStart+10*4)
 
-	// calculate cpLength. Used in PlcPcd verification (see section 2.8.35)
-	var cpLength int
-	if ccpFtn != 0 || ccpHdd != 0 || ccpMcr != 0 || ccpAtn != 0 || ccpEdn != 0 || ccpTxbx != 0 || ccpHdrTxbx != 0 {
-		cpLength = ccpFtn + ccpHdd + ccpMcr + ccpAtn + ccpEdn + ccpTxbx + ccpHdrTxbx + ccpText + 1
-	} else {
-		cpLength = ccpText
+	lw := &fibRgLw{ccpText: ccpText, ccpFtn: ccpFtn, ccpHdd: ccpHdd, ccpMcr: ccpMcr, ccpAtn: ccpAtn,
+		ccpEdn: ccpEdn, ccpTxbx: ccpTxbx, ccpHdrTxbx: ccpHdrTxbx}
+	lw.cpLength = lw.getCpLength()
+	return lw, cslw, nil
+}
+
+// calculate cpLength. Used in PlcPcd verification (see section 2.8.35)
+func (lw *fibRgLw) getCpLength() int {
+	if lw.ccpFtn != 0 || lw.ccpHdd != 0 || lw.ccpMcr != 0 || lw.ccpAtn != 0 || lw.ccpEdn != 0 || lw.ccpTxbx != 0 || lw.ccpHdrTxbx != 0 {
+		return lw.ccpFtn + lw.ccpHdd + lw.ccpMcr + lw.ccpAtn + lw.ccpEdn + lw.ccpTxbx + lw.ccpHdrTxbx + lw.ccpText + 1
 	}
-	return &fibRgLw{ccpText: ccpText, ccpFtn: ccpFtn, ccpHdd: ccpHdd, ccpMcr: ccpMcr, ccpAtn: ccpAtn,
-		ccpEdn: ccpEdn, ccpTxbx: ccpTxbx, ccpHdrTxbx: ccpHdrTxbx, cpLength: cpLength}, cslw, nil
+	return lw.ccpText
 }
 
-// parse FibRgFcLcb (section 2.5.5)
+// parse FibRgFcLcb (section 2.5.5). Values beyond cbRgFcLcb are left as 0, as are the values
+// of newer versions than the one that wrote the file (section 2.5.15)
 func getFibRgFcLcb(fib []byte, start int) (*fibRgFcLcb, int, error) {
-	fibRgFcLcbStart := start + 2          // skip cbRgFcLcb
-	if fibRgFcLcbStart+186*4 < len(fib) { // expect 186+ values in FibRgFcLcb
+	fibRgFcLcbStart := start + 2 // skip cbRgFcLcb
+	if fibRgFcLcbStart > len(fib) {
 		return &fibRgFcLcb{}, 0, errFibInvalid
 	}
-
 	cbRgFcLcb := getInt16(fib, start)
-	fcPlcfFldMom := getInt(fib, fibRgFcLcbStart+32*4)
-	lcbPlcfFldMom := getInt(fib, fibRgFcLcbStart+33*4)
-	fcPlcfFldHdr := getInt(fib, fibRgFcLcbStart+34*4)
-	lcbPlcfFldHdr := getInt(fib, fibRgFcLcbStart+35*4)
-	fcPlcfFldFtn := getInt(fib, fibRgFcLcbStart+36*4)
-	lcbPlcfFldFtn := getInt(fib, fibRgFcLcbStart+37*4)
-	fcPlcfFldAtn := getInt(fib, fibRgFcLcbStart+38*4)
-	lcbPlcfFldAtn := getInt(fib, fibRgFcLcbStart+39*4)
-	fcClx := getInt(fib, fibRgFcLcbStart+66*4)
-	lcbClx := getInt(fib, fibRgFcLcbStart+67*4)
-	return &fibRgFcLcb{fcPlcfFldMom: fcPlcfFldMom, lcbPlcfFldMom: lcbPlcfFldMom, fcPlcfFldHdr: fcPlcfFldHdr, lcbPlcfFldHdr: lcbPlcfFldHdr,
-		fcPlcfFldFtn: fcPlcfFldFtn, lcbPlcfFldFtn: lcbPlcfFldFtn, fcPlcfFldAtn: fcPlcfFldAtn, lcbPlcfFldAtn: lcbPlcfFldAtn,
-		fcClx: fcClx, lcbClx: lcbClx}, cbRgFcLcb, nil
+	if cbRgFcLcb < cbRgFcLcb97 || fibRgFcLcbStart+cbRgFcLcb*8 > len(fib) { // expect at least FibRgFcLcb97
+		return &fibRgFcLcb{}, 0, errFibInvalid
+	}
+
+	fcLcb := func(i int) int {
+		if i >= cbRgFcLcb*2 {
+			return 0
+		}
+		return getInt(fib, fibRgFcLcbStart+i*4)
+	}
+	const v2000, v2002, v2003, v2007 = cbRgFcLcb97 * 2, cbRgFcLcb2000 * 2, cbRgFcLcb2002 * 2, cbRgFcLcb2003 * 2
+	return &fibRgFcLcb{
+		fcStshf: fcLcb(2), lcbStshf: fcLcb(3),
+		fcPlcffndRef: fcLcb(4), lcbPlcffndRef: fcLcb(5),
+		fcPlcffndTxt: fcLcb(6), lcbPlcffndTxt: fcLcb(7),
+		fcPlcfandRef: fcLcb(8), lcbPlcfandRef: fcLcb(9),
+		fcPlcfandTxt: fcLcb(10), lcbPlcfandTxt: fcLcb(11),
+		fcPlcfSed: fcLcb(12), lcbPlcfSed: fcLcb(13),
+		fcPlcfBteChpx: fcLcb(24), lcbPlcfBteChpx: fcLcb(25),
+		fcPlcfBtePapx: fcLcb(26), lcbPlcfBtePapx: fcLcb(27),
+		fcPlcfFldMom: fcLcb(32), lcbPlcfFldMom: fcLcb(33),
+		fcPlcfFldHdr: fcLcb(34), lcbPlcfFldHdr: fcLcb(35),
+		fcPlcfFldFtn: fcLcb(36), lcbPlcfFldFtn: fcLcb(37),
+		fcPlcfFldAtn: fcLcb(38), lcbPlcfFldAtn: fcLcb(39),
+		fcSttbfFfn: fcLcb(30), lcbSttbfFfn: fcLcb(31),
+		fcDop: fcLcb(62), lcbDop: fcLcb(63),
+		fcClx: fcLcb(66), lcbClx: fcLcb(67),
+		fcGrpXstAtnOwners: fcLcb(72), lcbGrpXstAtnOwners: fcLcb(73),
+		fcPlcSpaMom: fcLcb(80), lcbPlcSpaMom: fcLcb(81),
+		fcPlcSpaHdr: fcLcb(82), lcbPlcSpaHdr: fcLcb(83),
+		fcPlcfendRef: fcLcb(92), lcbPlcfendRef: fcLcb(93),
+		fcPlcfendTxt: fcLcb(94), lcbPlcfendTxt: fcLcb(95),
+		fcPlcfFldEdn: fcLcb(96), lcbPlcfFldEdn: fcLcb(97),
+		fcDggInfo: fcLcb(100), lcbDggInfo: fcLcb(101),
+		fcSttbfRMark: fcLcb(102), lcbSttbfRMark: fcLcb(103),
+		fcPlcfFldTxbx: fcLcb(114), lcbPlcfFldTxbx: fcLcb(115),
+		fcPlcffldHdrTxbx: fcLcb(118), lcbPlcffldHdrTxbx: fcLcb(119),
+		fcPlfLst: fcLcb(146), lcbPlfLst: fcLcb(147),
+		fcPlfLfo: fcLcb(148), lcbPlfLfo: fcLcb(149),
+
+		fcPlcfTch: fcLcb(v2000 + 0), lcbPlcfTch: fcLcb(v2000 + 1),
+		fcRmdThreading: fcLcb(v2000 + 2), lcbRmdThreading: fcLcb(v2000 + 3),
+
+		fcPlrsid: fcLcb(v2002 + 10), lcbPlrsid: fcLcb(v2002 + 11),
+		fcSttbfBkmkFactoid: fcLcb(v2002 + 12), lcbSttbfBkmkFactoid: fcLcb(v2002 + 13),
+		fcPlcfBkfFactoid: fcLcb(v2002 + 14), lcbPlcfBkfFactoid: fcLcb(v2002 + 15),
+		fcPlcfBklFactoid: fcLcb(v2002 + 18), lcbPlcfBklFactoid: fcLcb(v2002 + 19),
+		fcFactoidData: fcLcb(v2002 + 20), lcbFactoidData: fcLcb(v2002 + 21),
+		fcPlcffactoid: fcLcb(v2002 + 48), lcbPlcffactoid: fcLcb(v2002 + 49),
+
+		fcHplxsdr: fcLcb(v2003 + 0), lcbHplxsdr: fcLcb(v2003 + 1),
+		fcSttbfBkmkSdt: fcLcb(v2003 + 2), lcbSttbfBkmkSdt: fcLcb(v2003 + 3),
+		fcPlcfBkfSdt: fcLcb(v2003 + 4), lcbPlcfBkfSdt: fcLcb(v2003 + 5),
+		fcPlcfBklSdt: fcLcb(v2003 + 6), lcbPlcfBklSdt: fcLcb(v2003 + 7),
+		fcCustomXForm: fcLcb(v2003 + 8), lcbCustomXForm: fcLcb(v2003 + 9),
+		fcSttbfBkmkProt: fcLcb(v2003 + 10), lcbSttbfBkmkProt: fcLcb(v2003 + 11),
+		fcPlcfBkfProt: fcLcb(v2003 + 12), lcbPlcfBkfProt: fcLcb(v2003 + 13),
+		fcPlcfBklProt: fcLcb(v2003 + 14), lcbPlcfBklProt: fcLcb(v2003 + 15),
+		fcSttbProtUser: fcLcb(v2003 + 16), lcbSttbProtUser: fcLcb(v2003 + 17),
+
+		fcPlcfmthd: fcLcb(v2007 + 0), lcbPlcfmthd: fcLcb(v2007 + 1),
+		fcSttbfBkmkMoveFrom: fcLcb(v2007 + 2), lcbSttbfBkmkMoveFrom: fcLcb(v2007 + 3),
+		fcPlcfBkfMoveFrom: fcLcb(v2007 + 4), lcbPlcfBkfMoveFrom: fcLcb(v2007 + 5),
+		fcPlcfBklMoveFrom: fcLcb(v2007 + 6), lcbPlcfBklMoveFrom: fcLcb(v2007 + 7),
+		fcSttbfBkmkMoveTo: fcLcb(v2007 + 8), lcbSttbfBkmkMoveTo: fcLcb(v2007 + 9),
+		fcPlcfBkfMoveTo: fcLcb(v2007 + 10), lcbPlcfBkfMoveTo: fcLcb(v2007 + 11),
+		fcPlcfBklMoveTo: fcLcb(v2007 + 12), lcbPlcfBklMoveTo: fcLcb(v2007 + 13),
+		fcOssTheme: fcLcb(v2007 + 34), lcbOssTheme: fcLcb(v2007 + 35),
+		fcColorSchemeMapping: fcLcb(v2007 + 36), lcbColorSchemeMapping: fcLcb(v2007 + 37),
+	}, cbRgFcLcb, nil
+}
+
+// parse cswNew and the nFibNew of FibRgCswNew, which follow FibRgFcLcb (section 2.5.11)
+func getFibRgCswNew(fib []byte, start int) (int, int) {
+	if start+2 > len(fib) {
+		return 0, 0
+	}
+	cswNew := getInt16(fib, start)
+	if cswNew == 0 || start+4 > len(fib) {
+		return 0, 0
+	}
+	return cswNew, getInt16(fib, start+2)
 }
 
 func getInt16(buf []byte, start int) int {
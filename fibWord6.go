@@ -0,0 +1,45 @@
+package doc2txt
+
+// nFib values written by Word 6.0 and Word 95, which use an older FIB layout
+const (
+	nFibWord6  = 0x0065
+	nFibWord95 = 0x0068
+)
+
+// the start of the WordDocument stream that XOR obfuscation leaves as it is, which is shorter than in later versions
+const fibWord6UnencryptedSize = 0x34
+
+// true if the document was saved by Word 6.0 or Word 95. These files have no table stream, so
+// the structures that the FIB points to are in the WordDocument stream, and all text is 8-bit
+func (f *fib) isWord6() bool {
+	return f.base.nFib >= nFibWord6 && f.base.nFib <= nFibWord95
+}
+
+// parse the Word 6.0 and Word 95 FIB, which has the counts of characters and the offsets and sizes
+// of the structures at fixed positions rather than in FibRgLw97 and FibRgFcLcb97. Only the structures
+// whose layout did not change in Word 97 are read, so the others are left empty
+func getFibWord6(b []byte, base *fibBase) (*fib, error) {
+	const fibWord6Size = 0x168 // up to and including lcbClx
+	if len(b) < fibWord6Size {
+		return nil, errFibInvalid
+	}
+
+	lw := fibRgLw{
+		ccpText: getInt(b, 0x34), ccpFtn: getInt(b, 0x38), ccpHdd: getInt(b, 0x3C), ccpMcr: getInt(b, 0x40),
+		ccpAtn: getInt(b, 0x44), ccpEdn: getInt(b, 0x48), ccpTxbx: getInt(b, 0x4C), ccpHdrTxbx: getInt(b, 0x50),
+	}
+	lw.cpLength = lw.getCpLength()
+
+	// the fc and lcb pairs start at fcStshfOrig, in the same order as FibRgFcLcb97
+	fcLcb := func(i int) int { return getInt(b, 0x58+i*4) }
+	fcLcbs := fibRgFcLcb{
+		fcPlcffndRef: fcLcb(4), lcbPlcffndRef: fcLcb(5),
+		fcPlcffndTxt: fcLcb(6), lcbPlcffndTxt: fcLcb(7),
+		fcPlcfFldMom: fcLcb(32), lcbPlcfFldMom: fcLcb(33),
+		fcPlcfFldHdr: fcLcb(34), lcbPlcfFldHdr: fcLcb(35),
+		fcPlcfFldFtn: fcLcb(36), lcbPlcfFldFtn: fcLcb(37),
+		fcPlcfFldAtn: fcLcb(38), lcbPlcfFldAtn: fcLcb(39),
+		fcClx: fcLcb(66), lcbClx: fcLcb(67),
+	}
+	return &fib{base: *base, fibRgLw: lw, fibRgFcLcb: fcLcbs}, nil
+}
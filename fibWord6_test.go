@@ -0,0 +1,128 @@
+package doc2txt
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// give simpleDoc.doc the FIB of Word 95, for its six 8-bit characters at 2048. A complex file has a
+// piece table for them and the other has fcMin and fcMac
+func word95TestDoc(t *testing.T, complex bool) []byte {
+	b, err := ioutil.ReadFile(`testData/simpleDoc.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := &memoryFile{b: b}
+	d, err := mscfb.New(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordDoc, _, _ := getWordDocAndTables(d)
+	const fcClx = 0x200
+	fib := make([]byte, fcClx+21)
+	wordDoc.ReadAt(fib[:0x0A], 0)
+	copy(fib, []byte{0xDC, 0xA5, 0x68, 0x00}) // wIdent and nFibWord95
+	fib[0x0A] = 0x04                          // fComplex
+	copy(fib[0x34:], uint32Bytes(6))          // ccpText
+	copy(fib[0x160:], uint32Bytes(fcClx))
+	copy(fib[0x164:], uint32Bytes(21)) // lcbClx
+	clx := append([]byte{0x02}, uint32Bytes(16)...)
+	clx = append(append(append(clx, uint32Bytes(0)...), uint32Bytes(6)...), 0, 0)
+	clx = append(append(clx, uint32Bytes(2048)...), 0, 0)
+	copy(fib[fcClx:], clx)
+	wordDoc.WriteAt(fib, 0)
+	return mem.b
+}
+
+func TestWord95(t *testing.T) {
+	doc := word95TestDoc(t, true)
+	d, _ := mscfb.New(bytes.NewReader(doc))
+	wordDoc, _, _ := getWordDocAndTables(d)
+	fib, err := getFib(wordDoc)
+	if err != nil || !fib.isWord6() || fib.fibRgLw.ccpText != 6 || fib.fibRgLw.cpLength != 6 || fib.fibRgFcLcb.fcClx != 0x200 {
+		t.Fatal("expected Word 95 FIB", fib, err)
+	}
+
+	f, _ := os.Open(`testData/simpleDoc.doc`)
+	r, _ := ParseDoc(f)
+	expected, _ := ioutil.ReadAll(r)
+	r, err = ParseDoc(bytes.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := ioutil.ReadAll(r)
+	if !bytes.Equal(text, expected) {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+
+	// the same document with XOR obfuscation, which in Word 95 is only flagged by fEncrypted
+	xor := obfuscateTestDoc(t, doc, true)
+	for _, passwords := range [][]string{{"password"}, {"wrong", "password"}} {
+		r, err := ParseDoc(bytes.NewReader(xor), WithPassword(passwords...))
+		if err != nil {
+			t.Error("expected to read obfuscated Word 95 document", passwords, err)
+			continue
+		}
+		if text, _ := ioutil.ReadAll(r); !bytes.Equal(text, expected) {
+			t.Errorf("expected %q from obfuscated Word 95 document, got %q", expected, text)
+		}
+	}
+	// the few bytes of this document are too little to recover the key from
+	var encrypted *EncryptedError
+	if _, err := ParseDoc(bytes.NewReader(xor)); !errors.As(err, &encrypted) || encrypted.Kind != EncryptionXOR {
+		t.Error("expected the obfuscated Word 95 document to need its password", err)
+	}
+
+	document, err := ParseDocument(bytes.NewReader(doc))
+	if err != nil || document.Metadata.FibVersion != nFibWord95 || len(document.Stories) == 0 {
+		t.Fatal("expected Word 95 document", err)
+	}
+}
+
+func TestNonComplex(t *testing.T) {
+	f, _ := os.Open(`testData/simpleDoc.doc`)
+	r, _ := ParseDoc(f)
+	expected, _ := ioutil.ReadAll(r)
+	r, err := ParseDoc(bytes.NewReader(word95TestDoc(t, false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := ioutil.ReadAll(r)
+	if !bytes.Equal(text, expected) {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+
+	// Word saved these files without fComplex, so their text is in order from fcMin, which the
+	// single piece read without the piece table must find as well
+	for _, name := range []string{`testData/docFile.doc`, `testData/simpleDoc.doc`} {
+		f, _ := os.Open(name)
+		defer f.Close()
+		d, _ := mscfb.New(f)
+		wordDoc, table0, table1 := getWordDocAndTables(d)
+		fib, _ := getFib(wordDoc)
+		pieces, err := getClx(getActiveTable(wordDoc, table0, table1, fib), fib)
+		if err != nil || fib.base.fComplex {
+			t.Fatal(name, "expected a piece table", err)
+		}
+		single, err := getNonComplexClx(fib)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		expected, _ := getCharacters(wordDoc, pieces)
+		text, _ := getCharacters(wordDoc, single)
+		if len(text) == 0 || string(utf16.Decode(text)) != string(utf16.Decode(expected)) {
+			t.Errorf("%s: expected %q, got %q", name, string(utf16.Decode(expected)), string(utf16.Decode(text)))
+		}
+	}
+
+	short := &fib{base: fibBase{fcMin: 2048, fcMac: 2050}, fibRgLw: fibRgLw{cpLength: 6}}
+	if _, err := getClx(table, short); err != errInvalidText {
+		t.Error("expected text to be too short", err)
+	}
+}
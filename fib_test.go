@@ -36,6 +36,9 @@ func TestGetFib(t *testing.T) {
 	if fib.base.fWhichTblStm != 1 {
 		t.Error("expected table 1")
 	}
+	if fib.base.nFib != 0x00C1 || fib.base.lid != 1033 {
+		t.Error("expected Word 97 format and US English", fib.base)
+	}
 	// No headers in simpleDoc, just "12345" in the text which apparently makes a ccpText of 6
 	// cpLength is calculated and should equal ccpText in this scenario
 	if fib.fibRgLw.ccpAtn != 0 || fib.fibRgLw.ccpEdn != 0 || fib.fibRgLw.ccpFtn != 0 || fib.fibRgLw.ccpHdd != 0 || fib.fibRgLw.ccpHdrTxbx != 0 ||
@@ -46,4 +49,42 @@ func TestGetFib(t *testing.T) {
 	if fib.fibRgFcLcb.fcClx != 5279 || fib.fibRgFcLcb.lcbClx != 21 {
 		t.Error("expected valid fibRgFcLcb", fib.fibRgFcLcb)
 	}
+	if fib.fibRgFcLcb.fcStshf != 0 || fib.fibRgFcLcb.lcbStshf != 1426 || fib.fibRgFcLcb.fcPlcfBteChpx != 4904 || fib.fibRgFcLcb.lcbPlcfBteChpx != 12 ||
+		fib.fibRgFcLcb.fcPlcfBtePapx != 4916 || fib.fibRgFcLcb.lcbPlcfBtePapx != 12 || fib.fibRgFcLcb.lcbPlcfSed != 20 || fib.fibRgFcLcb.lcbSttbfRMark != 22 {
+		t.Error("expected valid fibRgFcLcb", fib.fibRgFcLcb)
+	}
+}
+
+func TestGetFibRgFcLcb(t *testing.T) {
+	fib, _ := getFib(simpleDoc)
+	if fib.cswNew != 5 || fib.nFibNew != 0x0112 || fib.getNFib() != 0x0112 {
+		t.Error("expected Word 2007 format", fib.cswNew, fib.nFibNew)
+	}
+
+	// a Word 97 FibRgFcLcb has no values from later versions
+	b := make([]byte, 2+cbRgFcLcb2007*8)
+	for i := 2; i < len(b); i++ {
+		b[i] = 1
+	}
+	copy(b, []byte{cbRgFcLcb97, 0})
+	fcLcb, cbRgFcLcb, err := getFibRgFcLcb(b, 0)
+	if err != nil || cbRgFcLcb != cbRgFcLcb97 || fcLcb.lcbClx == 0 || fcLcb.fcPlcfTch != 0 || fcLcb.lcbColorSchemeMapping != 0 {
+		t.Error("expected only FibRgFcLcb97", fcLcb, err)
+	}
+	copy(b, []byte{cbRgFcLcb2007, 0})
+	if fcLcb, _, err = getFibRgFcLcb(b, 0); err != nil || fcLcb.fcPlcfTch == 0 || fcLcb.lcbColorSchemeMapping == 0 {
+		t.Error("expected FibRgFcLcb2007", fcLcb, err)
+	}
+
+	// larger buffers are fine, but smaller ones and counts below FibRgFcLcb97 are not
+	if _, _, err = getFibRgFcLcb(append(b, 0, 0), 0); err != nil {
+		t.Error("expected larger buffer to be valid", err)
+	}
+	if _, _, err = getFibRgFcLcb(b[:len(b)-1], 0); err != errFibInvalid {
+		t.Error("expected short buffer to be invalid", err)
+	}
+	copy(b, []byte{cbRgFcLcb97 - 1, 0})
+	if _, _, err = getFibRgFcLcb(b, 0); err != errFibInvalid {
+		t.Error("expected small count to be invalid", err)
+	}
 }
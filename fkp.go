@@ -0,0 +1,121 @@
+package doc2txt
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	errInvalidFkp = errors.New("invalid FKP structure")
+)
+
+const fkpSize = 512
+
+// fkpRun is a range of WordDocument stream offsets that share a set of properties
+type fkpRun struct {
+	fcStart int
+	fcEnd   int
+	istd    int // paragraph style, only set for PAPX runs
+	grpprl  []byte
+}
+
+// read all character property runs from the PlcBteChpx and its ChpxFkps (section 2.8.5)
+func getChpxRuns(wordDoc, table *mscfb.File, fib *fib) ([]fkpRun, error) {
+	return getFkpRuns(wordDoc, table, fib.fibRgFcLcb.fcPlcfBteChpx, fib.fibRgFcLcb.lcbPlcfBteChpx, parseChpxFkp)
+}
+
+// read all paragraph property runs from the PlcBtePapx and its PapxFkps (section 2.8.6)
+func getPapxRuns(wordDoc, table *mscfb.File, fib *fib) ([]fkpRun, error) {
+	return getFkpRuns(wordDoc, table, fib.fibRgFcLcb.fcPlcfBtePapx, fib.fibRgFcLcb.lcbPlcfBtePapx, parsePapxFkp)
+}
+
+func getFkpRuns(wordDoc, table *mscfb.File, fc, lcb int, parseFkp func([]byte) ([]fkpRun, error)) ([]fkpRun, error) {
+	if wordDoc == nil || table == nil {
+		return nil, errInvalidArgument
+	}
+	bte, err := getPlc(table, fc, lcb, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []fkpRun
+	for _, pn := range bte.aData {
+		page := make([]byte, fkpSize)
+		if _, err := wordDoc.ReadAt(page, int64(getInt(pn, 0)&0x3FFFFF)*fkpSize); err != nil { // pn is the low 22 bits (section 2.9.206)
+			return nil, err
+		}
+		fkpRuns, err := parseFkp(page)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, fkpRuns...)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].fcStart < runs[j].fcStart })
+	return runs, nil
+}
+
+// parse ChpxFkp (section 2.9.33)
+func parseChpxFkp(page []byte) ([]fkpRun, error) {
+	crun := int(page[fkpSize-1])
+	if crun == 0 || (crun+1)*4+crun > fkpSize-1 {
+		return nil, errInvalidFkp
+	}
+
+	runs := make([]fkpRun, crun)
+	for i := 0; i < crun; i++ {
+		runs[i] = fkpRun{fcStart: getInt(page, i*4), fcEnd: getInt(page, (i+1)*4)}
+		offset := int(page[(crun+1)*4+i]) * 2
+		if offset == 0 { // no Chpx for this run
+			continue
+		}
+		cb := int(page[offset])
+		if offset+1+cb > fkpSize-1 {
+			return nil, errInvalidFkp
+		}
+		runs[i].grpprl = page[offset+1 : offset+1+cb]
+	}
+	return runs, nil
+}
+
+// parse PapxFkp (section 2.9.174)
+func parsePapxFkp(page []byte) ([]fkpRun, error) {
+	const bxPapSize = 13
+	cpara := int(page[fkpSize-1])
+	if cpara == 0 || (cpara+1)*4+cpara*bxPapSize > fkpSize-1 {
+		return nil, errInvalidFkp
+	}
+
+	runs := make([]fkpRun, cpara)
+	for i := 0; i < cpara; i++ {
+		runs[i] = fkpRun{fcStart: getInt(page, i*4), fcEnd: getInt(page, (i+1)*4)}
+		offset := int(page[(cpara+1)*4+i*bxPapSize]) * 2
+		if offset == 0 {
+			continue
+		}
+
+		// PapxInFkp (section 2.9.175)
+		size := 2*int(page[offset]) - 1
+		start := offset + 1
+		if page[offset] == 0 {
+			size = 2 * int(page[offset+1])
+			start = offset + 2
+		}
+		if size < 2 || start+size > fkpSize-1 {
+			return nil, errInvalidFkp
+		}
+		runs[i].istd = getInt16(page, start)
+		runs[i].grpprl = page[start+2 : start+size]
+	}
+	return runs, nil
+}
+
+// find the run containing the given stream offset
+func findFkpRun(runs []fkpRun, fc int) *fkpRun {
+	i := sort.Search(len(runs), func(i int) bool { return runs[i].fcEnd > fc })
+	if i < len(runs) && runs[i].fcStart <= fc {
+		return &runs[i]
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package doc2txt
+
+import (
+	"errors"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	errInvalidFfn = errors.New("invalid font table (SttbfFfn) structure")
+)
+
+const ffnNameOffset = 39 // the size of the fixed part of an FFN, before xszFfn
+
+// font families from the ff field of an FFID (section 2.9.81)
+var fontFamilies = map[int]string{
+	1: "roman",
+	2: "swiss",
+	3: "modern",
+	4: "script",
+	5: "decorative",
+}
+
+// font pitches from the prq field of an FFID
+var fontPitches = map[int]string{
+	1: "fixed",
+	2: "variable",
+}
+
+const symbolCharset = 2 // SYMBOL_CHARSET
+
+// ffn is a font from the font table (section 2.9.82)
+type ffn struct {
+	name     string
+	altName  string
+	charset  int
+	family   int // ff, which is 0 if the family is not known
+	pitch    int // prq, which is 0 for the default pitch
+	trueType bool
+}
+
+// read the fonts from the SttbfFfn, indexed by ftc (section 2.9.286)
+func getFonts(table *mscfb.File, fib *fib) ([]ffn, error) {
+	b, err := readBlock(table, fib.fibRgFcLcb.fcSttbfFfn, fib.fibRgFcLcb.lcbSttbfFfn)
+	if err != nil {
+		return nil, err
+	}
+	return parseSttbfFfn(b)
+}
+
+// the SttbfFfn is an STTB whose strings are FFN records, each preceded by its size in bytes
+func parseSttbfFfn(b []byte) ([]ffn, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	if len(b) < 4 {
+		return nil, errInvalidFfn
+	}
+	cData := getInt16(b, 0)
+	fonts := make([]ffn, 0, cData)
+	offset := 4
+	for i := 0; i < cData; i++ {
+		if offset >= len(b) {
+			return nil, errInvalidFfn
+		}
+		cch := int(b[offset])
+		offset++
+		if offset+cch > len(b) {
+			return nil, errInvalidFfn
+		}
+		fonts = append(fonts, parseFfn(b[offset:offset+cch]))
+		offset += cch
+	}
+	return fonts, nil
+}
+
+func parseFfn(b []byte) ffn {
+	if len(b) < ffnNameOffset {
+		return ffn{}
+	}
+	// xszFfn is a null-terminated name, followed by the alternate name starting at ixchSzAlt
+	var names []string
+	var name []byte
+	for i := ffnNameOffset; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			names = append(names, decodeUTF16(name))
+			name = nil
+			continue
+		}
+		name = append(name, b[i], b[i+1])
+	}
+	f := ffn{charset: int(b[3]), family: int(b[0] >> 4 & 0x07), pitch: int(b[0] & 0x03), trueType: b[0]&0x04 != 0}
+	if len(names) > 0 {
+		f.name = names[0]
+	}
+	if b[4] != 0 && len(names) > 1 { // ixchSzAlt
+		f.altName = names[1]
+	}
+	return f
+}
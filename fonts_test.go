@@ -0,0 +1,45 @@
+package doc2txt
+
+import (
+	"os"
+	"testing"
+
+	"github.com/richardlehane/mscfb"
+)
+
+func TestGetFonts(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	doc, _ := mscfb.New(f)
+	wordDoc, _, table := getWordDocAndTables(doc)
+	fib, _ := getFib(wordDoc)
+	fonts, err := getFonts(table, fib)
+	if err != nil {
+		t.Fatal("expected to read fonts", err)
+	}
+	if len(fonts) != 9 || fonts[0].name != "Times New Roman" || fonts[2].name != "Arial" || fonts[7].name != "Wingdings" {
+		t.Error("expected font table", fonts)
+	}
+	if f := fonts[6]; f.name != "Courier New" || f.charset != 0 || fontFamilies[f.family] != "modern" || fontPitches[f.pitch] != "fixed" || !f.trueType {
+		t.Error("expected fixed pitch modern font", f)
+	}
+	if fonts[7].charset != symbolCharset || fonts[1].charset != symbolCharset {
+		t.Error("expected symbol fonts", fonts[1], fonts[7])
+	}
+}
+
+func TestParseSttbfFfn(t *testing.T) {
+	ffn := make([]byte, ffnNameOffset)
+	ffn[0] = 0x26 // ff of swiss, TrueType and variable pitch
+	ffn[3] = 0xCC // chs of RUSSIAN_CHARSET
+	ffn[4] = 2    // ixchSzAlt
+	ffn = append(ffn, 'A', 0, 0, 0, 'B', 0, 0, 0)
+	b := append([]byte{1, 0, 0, 0, byte(len(ffn))}, ffn...)
+	fonts, err := parseSttbfFfn(b)
+	if err != nil || len(fonts) != 1 || fonts[0].name != "A" || fonts[0].altName != "B" ||
+		fonts[0].charset != 204 || fonts[0].family != 2 || fonts[0].pitch != 2 || !fonts[0].trueType {
+		t.Error("expected one font with an alternate name", fonts, err)
+	}
+	if _, err := parseSttbfFfn(b[:len(b)-1]); err != errInvalidFfn {
+		t.Error("expected truncated font table to fail", err)
+	}
+}
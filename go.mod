@@ -5,4 +5,5 @@ go 1.13
 require (
 	github.com/mattetti/filebuffer v1.0.0
 	github.com/richardlehane/mscfb v1.0.3
+	golang.org/x/text v0.3.3
 )
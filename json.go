@@ -0,0 +1,16 @@
+package doc2txt
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONSchemaVersion is the version of the JSON written by WriteJSON. It changes
+// whenever a field is removed or changes meaning. New fields can be added without
+// changing the version, so consumers should ignore fields they don't recognize
+const JSONSchemaVersion = 1
+
+// WriteJSON writes the document as JSON
+func (d *Document) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(d)
+}
@@ -0,0 +1,100 @@
+package doc2txt
+
+import "strings"
+
+// equations from EQ fields and Equation Editor objects are written as LaTeX, with the symbols
+// that have a LaTeX command written as the command so that they can be searched for as text
+
+// the LaTeX commands of the symbols used in equations
+var latexSymbols = map[rune]string{
+	0x0391: "A", 0x0392: "B", 0x0393: `\Gamma`, 0x0394: `\Delta`, 0x0395: "E", 0x0396: "Z", 0x0397: "H",
+	0x0398: `\Theta`, 0x0399: "I", 0x039A: "K", 0x039B: `\Lambda`, 0x039C: "M", 0x039D: "N", 0x039E: `\Xi`,
+	0x039F: "O", 0x03A0: `\Pi`, 0x03A1: "P", 0x03A3: `\Sigma`, 0x03A4: "T", 0x03A5: `\Upsilon`, 0x03A6: `\Phi`,
+	0x03A7: "X", 0x03A8: `\Psi`, 0x03A9: `\Omega`,
+	0x03B1: `\alpha`, 0x03B2: `\beta`, 0x03B3: `\gamma`, 0x03B4: `\delta`, 0x03B5: `\epsilon`, 0x03B6: `\zeta`,
+	0x03B7: `\eta`, 0x03B8: `\theta`, 0x03B9: `\iota`, 0x03BA: `\kappa`, 0x03BB: `\lambda`, 0x03BC: `\mu`,
+	0x03BD: `\nu`, 0x03BE: `\xi`, 0x03BF: "o", 0x03C0: `\pi`, 0x03C1: `\rho`, 0x03C2: `\varsigma`, 0x03C3: `\sigma`,
+	0x03C4: `\tau`, 0x03C5: `\upsilon`, 0x03C6: `\varphi`, 0x03C7: `\chi`, 0x03C8: `\psi`, 0x03C9: `\omega`,
+	0x03D1: `\vartheta`, 0x03D5: `\phi`, 0x03D6: `\varpi`,
+	0x00B1: `\pm`, 0x00D7: `\times`, 0x00F7: `\div`, 0x00B7: `\cdot`, 0x22C5: `\cdot`, 0x2213: `\mp`,
+	0x2264: `\le`, 0x2265: `\ge`, 0x2260: `\ne`, 0x2248: `\approx`, 0x2261: `\equiv`, 0x2245: `\cong`,
+	0x223C: `\sim`, 0x221D: `\propto`, 0x226A: `\ll`, 0x226B: `\gg`,
+	0x221E: `\infty`, 0x2202: `\partial`, 0x2207: `\nabla`, 0x2032: `'`, 0x2033: `''`, 0x00B0: `^\circ`,
+	0x2208: `\in`, 0x2209: `\notin`, 0x2282: `\subset`, 0x2283: `\supset`, 0x2286: `\subseteq`, 0x2287: `\supseteq`,
+	0x222A: `\cup`, 0x2229: `\cap`, 0x2205: `\emptyset`, 0x2200: `\forall`, 0x2203: `\exists`, 0x00AC: `\neg`,
+	0x2227: `\wedge`, 0x2228: `\vee`, 0x2295: `\oplus`, 0x2297: `\otimes`, 0x2234: `\therefore`,
+	0x2192: `\to`, 0x2190: `\leftarrow`, 0x2194: `\leftrightarrow`, 0x21D2: `\Rightarrow`, 0x21D0: `\Leftarrow`,
+	0x21D4: `\Leftrightarrow`, 0x2191: `\uparrow`, 0x2193: `\downarrow`,
+	0x221A: `\surd`, 0x222B: `\int`, 0x222C: `\iint`, 0x222D: `\iiint`, 0x222E: `\oint`, 0x2211: `\sum`,
+	0x220F: `\prod`, 0x2210: `\coprod`, 0x22C3: `\bigcup`, 0x22C2: `\bigcap`,
+	0x2026: `\ldots`, 0x22EF: `\cdots`, 0x22EE: `\vdots`, 0x22F1: `\ddots`, 0x2135: `\aleph`, 0x210F: `\hbar`,
+	0x2113: `\ell`, 0x211C: `\Re`, 0x2111: `\Im`, 0x2118: `\wp`, 0x2220: `\angle`, 0x22A5: `\perp`, 0x2225: `\parallel`,
+	0x2329: `\langle`, 0x232A: `\rangle`, 0x27E8: `\langle`, 0x27E9: `\rangle`, 0x2212: "-",
+	'{': `\{`, '}': `\}`, '%': `\%`, '&': `\&`, '#': `\#`, '$': `\$`, '_': `\_`, '\\': `\backslash`,
+	'~': `\sim`, '^': `\hat{}`,
+}
+
+// write a character of an equation as LaTeX
+func latexChar(r rune) string {
+	if s, ok := latexSymbols[r]; ok {
+		return s
+	}
+	return string(r)
+}
+
+// write text as LaTeX, character by character
+func latexText(s string) string {
+	var buf strings.Builder
+	for _, r := range s {
+		buf.WriteString(latexChar(r))
+	}
+	return buf.String()
+}
+
+// join LaTeX commands and text, separating a command from a letter that follows it
+func latexJoin(parts ...string) string {
+	var buf strings.Builder
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if s := buf.String(); len(s) > 0 && endsWithCommand(s) && isLetter(p[0]) {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(p)
+	}
+	return buf.String()
+}
+
+// whether LaTeX ends with a command name such as \alpha, which a letter after it would extend
+func endsWithCommand(s string) bool {
+	i := len(s)
+	for i > 0 && isLetter(s[i-1]) {
+		i--
+	}
+	return i < len(s) && i > 0 && s[i-1] == '\\'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// the LaTeX for the left and right of a pair of brackets
+var latexBrackets = map[rune][2]string{
+	'(': {"(", ")"}, ')': {"(", ")"}, '[': {"[", "]"}, ']': {"[", "]"}, '{': {`\{`, `\}`}, '}': {`\{`, `\}`},
+	'<': {`\langle`, `\rangle`}, '>': {`\langle`, `\rangle`}, '|': {"|", "|"}, 0x2016: {`\|`, `\|`},
+	0x2329: {`\langle`, `\rangle`}, 0x232A: {`\langle`, `\rangle`}, 0x230A: {`\lfloor`, `\rfloor`},
+	0x230B: {`\lfloor`, `\rfloor`}, 0x2308: {`\lceil`, `\rceil`}, 0x2309: {`\lceil`, `\rceil`},
+}
+
+// write content between brackets that grow to its height. An empty left or right leaves that
+// side without a bracket
+func latexFence(left, right, content string) string {
+	if left == "" {
+		left = "."
+	}
+	if right == "" {
+		right = "."
+	}
+	return latexJoin(`\left`+left, " ", content, " ", `\right`+right)
+}
@@ -0,0 +1,126 @@
+package doc2txt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrLegacyFormat is returned by ParseDocument and ParseMetadata for files from Word versions before
+// Word 6.0, which are not compound files. Their text can still be read with ParseDoc. The error
+// returned is a *LegacyFormatError, which gives the format
+var ErrLegacyFormat = errors.New("document is in a legacy Word format")
+
+var (
+	errUnknownFormat = errors.New("not a Word document: unrecognised file signature")
+	errLegacyText    = errors.New("legacy file text is outside the file")
+)
+
+// LegacyFormat is a Word format from before Word 6.0
+type LegacyFormat string
+
+// The legacy formats that are recognised
+const (
+	FormatWordDOS LegacyFormat = "Word for DOS"
+	FormatWord1   LegacyFormat = "Word for Windows 1.x"
+	FormatWord2   LegacyFormat = "Word for Windows 2.0"
+)
+
+const legacyDOSTextFc = 128 // Word for DOS text always follows the 128 byte header
+
+// LegacyFormatError reports that a document is in a legacy format. It matches ErrLegacyFormat with errors.Is
+type LegacyFormatError struct {
+	Format LegacyFormat
+	file   *legacyFile // the header, which ParseDoc reads the text with
+}
+
+func (e *LegacyFormatError) Error() string {
+	return ErrLegacyFormat.Error() + " (" + string(e.Format) + ")"
+}
+
+// Is reports whether target is ErrLegacyFormat
+func (e *LegacyFormatError) Is(target error) bool {
+	return target == ErrLegacyFormat
+}
+
+// the signature at the start of every compound file ([MS-CFB] section 2.2)
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// legacyFile is the header of a Word file from before Word 6.0, which is enough to find the text
+type legacyFile struct {
+	format LegacyFormat
+	fcMin  int // the text is stored in order as 8-bit characters from fcMin to fcMac
+	fcMac  int
+}
+
+// check the signature of a file. Compound files return nil, legacy Word files return their header
+// and any other file returns errUnknownFormat
+func sniffFormat(ra io.ReaderAt) (*legacyFile, error) {
+	b := make([]byte, 32)
+	if n, err := ra.ReadAt(b, 0); n < len(b) {
+		if err == nil || err == io.EOF {
+			err = errUnknownFormat
+		}
+		return nil, err
+	}
+	if bytes.Equal(b[:8], cfbSignature) {
+		return nil, nil
+	}
+
+	switch wIdent := getInt16(b, 0); {
+	case (wIdent == 0xBE31 || wIdent == 0xBE32) && getInt16(b, 2) == 0 && getInt16(b, 4) == 0xAB00:
+		return &legacyFile{format: FormatWordDOS, fcMin: legacyDOSTextFc, fcMac: getInt(b, 14)}, nil
+	case wIdent == 0xA59B:
+		return &legacyFile{format: FormatWord1, fcMin: getInt(b, 24), fcMac: getInt(b, 28)}, nil
+	case wIdent == 0xA5DB:
+		return &legacyFile{format: FormatWord2, fcMin: getInt(b, 24), fcMac: getInt(b, 28)}, nil
+	}
+	return nil, errUnknownFormat
+}
+
+// read the text of a legacy file, translated the same way as compressed text in later versions
+func (l *legacyFile) text(ra io.ReaderAt, o *options) (io.Reader, error) {
+	if l.fcMin <= 0 || l.fcMac < l.fcMin {
+		return nil, errLegacyText
+	}
+	fcMac := readerAtSize(ra, l.fcMac) // the header is not trusted to give a size the file has
+	if fcMac < l.fcMin {
+		return nil, errLegacyText
+	}
+	b := make([]byte, fcMac-l.fcMin)
+	if n, _ := ra.ReadAt(b, int64(l.fcMin)); n < len(b) {
+		return nil, errLegacyText
+	}
+	chars := make([]uint16, len(b))
+	for i, c := range b {
+		chars[i] = ansiToUnicode(c)
+	}
+	var buf bytes.Buffer
+	translateText(chars, nil, o.special, &buf)
+	return bytes.NewBufferString(o.text.render(buf.String())), nil
+}
+
+// the size of the data in ra, up to max, found by reading single bytes
+func readerAtSize(ra io.ReaderAt, max int) int {
+	b := make([]byte, 1)
+	readable := func(offset int) bool {
+		n, _ := ra.ReadAt(b, int64(offset))
+		return n == 1
+	}
+	if max <= 0 || readable(max-1) {
+		return max
+	}
+	low, high := 0, max-1 // the byte at high cannot be read
+	if !readable(0) {
+		return 0
+	}
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		if readable(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return high
+}
@@ -0,0 +1,105 @@
+package doc2txt
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+// build a document of a format from before Word 6.0: a header with the signature of the format and
+// the offsets of the text, then the 8-bit text
+func legacyTestDoc(format LegacyFormat, text string) []byte {
+	fcMin, fcMinOffset, fcMacOffset := 0x180, 24, 28
+	var b []byte
+	switch format {
+	case FormatWordDOS: // fcMin is always 128 and is not stored
+		fcMin, fcMinOffset, fcMacOffset = 128, 0, 14
+		b = make([]byte, fcMin)
+		copy(b, []byte{0x31, 0xBE, 0x00, 0x00, 0x00, 0xAB})
+	case FormatWord1:
+		b = make([]byte, fcMin)
+		copy(b, []byte{0x9B, 0xA5, 0x21, 0x00})
+	case FormatWord2:
+		b = make([]byte, fcMin)
+		copy(b, []byte{0xDB, 0xA5, 0x2D, 0x00})
+	}
+	if fcMinOffset != 0 {
+		copy(b[fcMinOffset:], uint32Bytes(uint32(fcMin)))
+	}
+	copy(b[fcMacOffset:], uint32Bytes(uint32(fcMin+len(text))))
+	return append(b, text...)
+}
+
+func TestLegacyText(t *testing.T) {
+	tests := []struct {
+		doc    []byte
+		format LegacyFormat
+	}{
+		{legacyTestDoc(FormatWordDOS, "Word for DOS\r\n"), FormatWordDOS},
+		{legacyTestDoc(FormatWord1, "Word 1\r"), FormatWord1},
+		{legacyTestDoc(FormatWord2, "Word 2\r"), FormatWord2},
+	}
+	expected := []string{"Word for DOS\r\n", "Word 1\r", "Word 2\r"}
+	for i, test := range tests {
+		r, err := ParseDoc(bytes.NewReader(test.doc))
+		if err != nil {
+			t.Error(test.format, err)
+			continue
+		}
+		text, _ := ioutil.ReadAll(r)
+		if string(text) != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], text)
+		}
+
+		_, err = ParseDocument(bytes.NewReader(test.doc))
+		var legacy *LegacyFormatError
+		if !errors.Is(err, ErrLegacyFormat) || !errors.As(err, &legacy) || legacy.Format != test.format {
+			t.Error("expected legacy format error", test.format, err)
+		}
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	for _, doc := range [][]byte{[]byte("short"), bytes.Repeat([]byte("not a Word document "), 10)} {
+		if _, err := ParseDoc(bytes.NewReader(doc)); !errors.Is(err, errUnknownFormat) {
+			t.Error("expected unknown format", err)
+		}
+		if _, err := ParseMetadata(bytes.NewReader(doc)); !errors.Is(err, errUnknownFormat) {
+			t.Error("expected unknown format", err)
+		}
+	}
+
+	doc := legacyTestDoc(FormatWord2, "Word 2\r")
+	copy(doc[24:], uint32Bytes(0x1000))
+	copy(doc[28:], uint32Bytes(0x1004))
+	if _, err := ParseDoc(bytes.NewReader(doc)); !errors.Is(err, errLegacyText) {
+		t.Error("expected text outside the file", err)
+	}
+}
+
+func TestLegacyTextSize(t *testing.T) {
+	// an fcMac past the end of the file, here about 4 GiB, is capped at the size of the file
+	for _, fcMac := range []uint32{0x1000, 0xFFFFFFF0} {
+		doc := legacyTestDoc(FormatWord2, "Word 2\r")
+		copy(doc[28:], uint32Bytes(fcMac))
+		r, err := ParseDoc(bytes.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text, _ := ioutil.ReadAll(r); string(text) != "Word 2\r" {
+			t.Errorf("expected the text up to the end of the file, got %q", text)
+		}
+	}
+
+	ra := bytes.NewReader(make([]byte, 1000))
+	for _, max := range []int{0, 1, 999, 1000, 1001, 1 << 32} {
+		expected := max
+		if expected > 1000 {
+			expected = 1000
+		}
+		if size := readerAtSize(ra, max); size != expected {
+			t.Errorf("expected size %d up to %d, got %d", expected, max, size)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package doc2txt
+
+import (
+	"errors"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	errInvalidList = errors.New("invalid list table (PlfLst or PlfLfo) structure")
+)
+
+const (
+	cbLSTF    = 28
+	cbLVLF    = 28
+	cbLFO     = 16
+	cbLFOLVL  = 8
+	maxLevels = 9 // the number of levels of a list that is not a simple list
+)
+
+// what follows the number of a level, by the ixchFollow of its LVLF
+var listFollows = []string{"tab", "space", "nothing"}
+
+// the names of the number formats, from the MSONFC values ([MS-OSHARED] section 2.2.1.3)
+var nfcNames = map[int]string{
+	0x00: "decimal",
+	0x01: "upperRoman",
+	0x02: "lowerRoman",
+	0x03: "upperLetter",
+	0x04: "lowerLetter",
+	0x05: "ordinal",
+	0x06: "cardinalText",
+	0x07: "ordinalText",
+	0x09: "chicago",
+	0x16: "decimalZero",
+	0x17: "bullet",
+	0xFF: "none",
+}
+
+// lstf is a list definition from the PlfLst, with its levels
+type lstf struct {
+	lsid   int
+	levels []lvl
+}
+
+// lvl is a level of a list. The paragraph and character properties are those
+// given by grpprlPapx and grpprlChpx
+type lvl struct {
+	iStartAt   int
+	nfc        int
+	jc         int
+	ixchFollow int
+	rgbxchNums []byte // the positions in xst of the placeholders for the level numbers, from 1
+	xst        []uint16
+	pap        pap
+	chp        chp
+}
+
+// lfo is a list instance from the PlfLfo, which paragraphs refer to by their ilfo
+type lfo struct {
+	lsid   int
+	levels []lfoLvl
+}
+
+// lfoLvl overrides a level of a list for one instance
+type lfoLvl struct {
+	iStartAt int
+	ilvl     int
+	fStartAt bool
+	lvl      *lvl // the level that replaces the level of the list when fFormatting is set
+}
+
+// read the list definitions and instances. The LVLs of the lists are stored in the table stream
+// straight after the PlfLst, so they are read up to the end of the stream
+func getLists(table *mscfb.File, fib *fib) ([]lstf, []lfo, error) {
+	if fib.fibRgFcLcb.lcbPlfLst == 0 {
+		return nil, nil, nil
+	}
+	b, err := readBlock(table, fib.fibRgFcLcb.fcPlfLst, int(table.Size)-fib.fibRgFcLcb.fcPlfLst)
+	if err != nil {
+		return nil, nil, err
+	}
+	lists, err := parsePlfLst(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err = readBlock(table, fib.fibRgFcLcb.fcPlfLfo, fib.fibRgFcLcb.lcbPlfLfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	instances, err := parsePlfLfo(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lists, instances, nil
+}
+
+// parse the PlfLst and the LVLs that follow it, one for a simple list and nine otherwise
+func parsePlfLst(b []byte) ([]lstf, error) {
+	if len(b) < 2 {
+		return nil, errInvalidList
+	}
+	cLst := int(int16(getInt16(b, 0)))
+	if cLst < 0 || 2+cLst*cbLSTF > len(b) {
+		return nil, errInvalidList
+	}
+	lists := make([]lstf, cLst)
+	offset := 2 + cLst*cbLSTF
+	for i := range lists {
+		lstfStart := 2 + i*cbLSTF
+		lists[i].lsid = int(int32(getInt(b, lstfStart)))
+		levels := maxLevels
+		if b[lstfStart+26]&0x01 != 0 { // fSimpleList
+			levels = 1
+		}
+		for j := 0; j < levels; j++ {
+			l, size, err := parseLvl(b[offset:])
+			if err != nil {
+				return nil, err
+			}
+			lists[i].levels = append(lists[i].levels, l)
+			offset += size
+		}
+	}
+	return lists, nil
+}
+
+// parse an LVL, which is an LVLF followed by grpprlPapx, grpprlChpx and the number text as an
+// Xst. Returns the size of the LVL
+func parseLvl(b []byte) (lvl, int, error) {
+	if len(b) < cbLVLF {
+		return lvl{}, 0, errInvalidList
+	}
+	l := lvl{iStartAt: int(int32(getInt(b, 0))), nfc: int(b[4]), jc: int(b[5] & 0x03), rgbxchNums: b[6:15], ixchFollow: int(b[15])}
+	cbGrpprlChpx, cbGrpprlPapx := int(b[24]), int(b[25])
+	offset := cbLVLF
+	if offset+cbGrpprlPapx+cbGrpprlChpx+2 > len(b) {
+		return lvl{}, 0, errInvalidList
+	}
+	l.pap.apply(b[offset : offset+cbGrpprlPapx])
+	offset += cbGrpprlPapx
+	l.chp = defaultChp()
+	l.chp.ftc = -1 // no font unless grpprlChpx has one
+	l.chp.apply(b[offset:offset+cbGrpprlChpx], l.chp, nil)
+	offset += cbGrpprlChpx
+
+	cch := getInt16(b, offset)
+	offset += 2
+	if offset+cch*2 > len(b) {
+		return lvl{}, 0, errInvalidList
+	}
+	l.xst = make([]uint16, cch)
+	for i := range l.xst {
+		l.xst[i] = uint16(getInt16(b, offset+i*2))
+	}
+	return l, offset + cch*2, nil
+}
+
+// parse the PlfLfo, which has the LFOs followed by an LFOData for each of them. The LFOData has
+// an LFOLVL for each level that is overridden, each followed by an LVL if it replaces the level
+func parsePlfLfo(b []byte) ([]lfo, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	if len(b) < 4 {
+		return nil, errInvalidList
+	}
+	lfoMac := getInt(b, 0)
+	if lfoMac > (len(b)-4)/cbLFO {
+		return nil, errInvalidList
+	}
+	instances := make([]lfo, lfoMac)
+	offset := 4 + lfoMac*cbLFO
+	for i := range instances {
+		lfoStart := 4 + i*cbLFO
+		instances[i].lsid = int(int32(getInt(b, lfoStart)))
+		clfolvl := int(b[lfoStart+12])
+
+		offset += 4 // the cp of the LFOData, which is not used
+		for j := 0; j < clfolvl; j++ {
+			if offset+cbLFOLVL > len(b) {
+				return nil, errInvalidList
+			}
+			flags := getInt(b, offset+4)
+			l := lfoLvl{iStartAt: int(int32(getInt(b, offset))), ilvl: flags & 0x0F, fStartAt: flags&0x10 != 0}
+			offset += cbLFOLVL
+			if flags&0x20 != 0 { // fFormatting
+				lv, size, err := parseLvl(b[offset:])
+				if err != nil {
+					return nil, err
+				}
+				l.lvl = &lv
+				offset += size
+			}
+			instances[i].levels = append(instances[i].levels, l)
+		}
+	}
+	return instances, nil
+}
+
+// the model of a level. Characters in the number text that are not placeholders are mapped
+// from symbol fonts to Unicode, as bullets usually are in Symbol or Wingdings
+func (l *lvl) model(fonts []ffn) ListLevel {
+	format, ok := nfcNames[l.nfc]
+	if !ok { // the number formats that are not in nfcNames are written as decimal numbers
+		format = "decimal"
+	}
+	follow := "tab"
+	if l.ixchFollow < len(listFollows) {
+		follow = listFollows[l.ixchFollow]
+	}
+
+	placeholders := map[int]bool{}
+	for _, ixch := range l.rgbxchNums {
+		if ixch == 0 {
+			break
+		}
+		placeholders[int(ixch)-1] = true
+	}
+	var text []uint16
+	for i, c := range l.xst {
+		if placeholders[i] && c < maxLevels {
+			text = append(text, utf16.Encode([]rune("%"+strconv.Itoa(int(c)+1)))...)
+			continue
+		}
+		text = append(text, fontCharacter(fonts, l.chp.ftc, c)...)
+	}
+	return ListLevel{Start: l.iStartAt, Format: format, Text: string(utf16.Decode(text)), Alignment: alignment(l.jc),
+		Follow: follow, Indent: l.pap.dxaLeft, FirstLine: l.pap.dxaLeft1}
+}
+
+// add the lists and list instances to the document. The instances are numbered from 1 in the
+// order of the PlfLfo, as the ilfo of paragraphs is
+func (b *docBuilder) lists(lists []lstf, instances []lfo) {
+	for _, l := range lists {
+		list := List{ID: l.lsid}
+		for i := range l.levels {
+			list.Levels = append(list.Levels, l.levels[i].model(b.fonts))
+		}
+		b.doc.Lists = append(b.doc.Lists, list)
+	}
+	for i, l := range instances {
+		instance := ListInstance{ID: i + 1, ListID: l.lsid}
+		for _, level := range l.levels {
+			if !level.fStartAt && level.lvl == nil {
+				continue
+			}
+			override := ListOverride{Level: level.ilvl, Start: level.iStartAt}
+			if level.lvl != nil {
+				definition := level.lvl.model(b.fonts)
+				override.Definition = &definition
+				if !level.fStartAt {
+					override.Start = definition.Start
+				}
+			}
+			instance.Overrides = append(instance.Overrides, override)
+		}
+		b.doc.ListInstances = append(b.doc.ListInstances, instance)
+	}
+}
+
+// the list item of a paragraph with the given properties, or nil if it is not in a list. An ilfo
+// of 0xF801 takes a paragraph out of the list its style puts it in
+func (b *docBuilder) listItem(p pap) *ListItem {
+	if p.ilfo <= 0 || p.ilfo > len(b.doc.ListInstances) || p.ilvl >= maxLevels {
+		return nil
+	}
+	return &ListItem{Instance: p.ilfo, Level: p.ilvl}
+}
@@ -0,0 +1,88 @@
+package doc2txt
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/richardlehane/mscfb"
+)
+
+func TestGetLists(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	doc, _ := mscfb.New(f)
+	wordDoc, _, table := getWordDocAndTables(doc)
+	fib, _ := getFib(wordDoc)
+	lists, instances, err := getLists(table, fib)
+	if err != nil {
+		t.Fatal("expected to read lists", err)
+	}
+	if len(lists) != 2 || lists[0].lsid != 0x2C1D0191 || lists[1].lsid != 0x40ED09C3 || len(lists[0].levels) != maxLevels {
+		t.Fatal("expected two lists of nine levels", lists)
+	}
+	if l := lists[1].levels[2]; l.iStartAt != 1 || l.nfc != 2 || l.jc != 2 || l.pap.dxaLeft != 2160 || l.pap.dxaLeft1 != -180 {
+		t.Error("expected the third level of the numbered list", l)
+	}
+	if len(instances) != 2 || instances[0].lsid != lists[0].lsid || instances[1].lsid != lists[1].lsid || len(instances[1].levels) != 0 {
+		t.Error("expected two list instances without overrides", instances)
+	}
+}
+
+// an LFO whose second level starts at 4 and whose first is replaced by an upper Roman level
+func TestParsePlfLfo(t *testing.T) {
+	b := make([]byte, 4+cbLFO+4)
+	binary.LittleEndian.PutUint32(b, 1)
+	binary.LittleEndian.PutUint32(b[4:], 7) // lsid
+	b[4+12] = 2                             // clfolvl
+	b = append(b, 4, 0, 0, 0, 0x11, 0, 0, 0, 0, 0, 0, 0, 0x20, 0, 0, 0)
+	lvlf := make([]byte, cbLVLF)
+	lvlf[0], lvlf[4], lvlf[6] = 2, 1, 1 // iStartAt, nfc and the position of the level number
+	b = append(append(b, lvlf...), 2, 0, 0, 0, '.', 0)
+
+	instances, err := parsePlfLfo(b)
+	if err != nil || len(instances) != 1 || instances[0].lsid != 7 || len(instances[0].levels) != 2 {
+		t.Fatal("expected one list instance with two overrides", instances, err)
+	}
+	if l := instances[0].levels[0]; l.ilvl != 1 || !l.fStartAt || l.iStartAt != 4 || l.lvl != nil {
+		t.Error("expected a start override", l)
+	}
+	l := instances[0].levels[1]
+	if l.ilvl != 0 || l.fStartAt || l.lvl == nil {
+		t.Fatal("expected a formatting override", l)
+	}
+	if level := l.lvl.model(nil); level.Start != 2 || level.Format != "upperRoman" || level.Text != "%1." {
+		t.Error("expected the replaced level", level)
+	}
+	if _, err := parsePlfLfo(b[:len(b)-1]); err != errInvalidList {
+		t.Error("expected truncated list instances to fail", err)
+	}
+}
+
+func TestDocumentLists(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	defer f.Close()
+	d, err := ParseDocument(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+	if len(d.Lists) != 2 || len(d.ListInstances) != 2 || d.ListInstances[1].ListID != d.Lists[1].ID {
+		t.Fatal("expected lists and list instances", d.Lists, d.ListInstances)
+	}
+	bullet, number := d.Lists[0].Levels[0], d.Lists[1].Levels[0]
+	if bullet.Format != "bullet" || bullet.Text != "•" || bullet.Indent != 720 || bullet.FirstLine != -360 {
+		t.Error("expected a bullet mapped from the Symbol font", bullet)
+	}
+	if number.Format != "decimal" || number.Text != "%1." || number.Start != 1 || number.Follow != "tab" {
+		t.Error("expected a numbered level", number)
+	}
+
+	items := map[string]ListItem{}
+	for _, block := range d.Stories[0].Sections[0].Blocks {
+		if p := block.Paragraph; p != nil && p.List != nil {
+			items[p.Text] = *p.List
+		}
+	}
+	if len(items) != 6 || items["Bullet 2"] != (ListItem{1, 0}) || items["Item 3"] != (ListItem{2, 0}) {
+		t.Error("expected the list items", items)
+	}
+}
@@ -0,0 +1,131 @@
+package doc2txt
+
+import (
+	"sort"
+	"time"
+)
+
+// property identifiers in the SummaryInformation property set ([MS-OLEPS] section 2.25.1)
+const (
+	pidsiTitle       = 0x02
+	pidsiSubject     = 0x03
+	pidsiAuthor      = 0x04
+	pidsiKeywords    = 0x05
+	pidsiComments    = 0x06
+	pidsiLastAuthor  = 0x08
+	pidsiRevNumber   = 0x09
+	pidsiLastPrinted = 0x0B
+	pidsiCreateDTM   = 0x0C
+	pidsiLastSaveDTM = 0x0D
+	pidsiPageCount   = 0x0E
+	pidsiWordCount   = 0x0F
+	pidsiCharCount   = 0x10
+)
+
+// property identifiers in the DocumentSummaryInformation property set ([MS-OLEPS] section 2.25.2)
+const (
+	piddsiManager = 0x0E
+	piddsiCompany = 0x0F
+)
+
+func getMetadata(w *wordFile) Metadata {
+	m := Metadata{FibVersion: w.fib.base.nFib, Language: w.fib.base.lid}
+	if w.fib.cswNew != 0 {
+		m.FibVersionNew = w.fib.nFibNew
+	}
+	if t := w.cfb.Created().UTC(); t.Unix() > 0 { // a zero FILETIME means the time was not recorded
+		m.Created = &t
+	}
+	if t := w.cfb.Modified().UTC(); t.Unix() > 0 {
+		m.Modified = &t
+	}
+
+	// the summary information is optional, so property sets that cannot be read are left out
+	// rather than preventing the rest of the document from being read
+	summary, _ := w.propertySets("SummaryInformation")
+	docSummary, _ := w.propertySets("DocumentSummaryInformation")
+	for _, set := range append(summary, docSummary...) {
+		switch set.fmtid {
+		case fmtidSummaryInformation:
+			m.Title = set.stringValue(pidsiTitle)
+			m.Subject = set.stringValue(pidsiSubject)
+			m.Author = set.stringValue(pidsiAuthor)
+			m.Keywords = set.stringValue(pidsiKeywords)
+			m.Comments = set.stringValue(pidsiComments)
+			m.LastSavedBy = set.stringValue(pidsiLastAuthor)
+			m.RevisionNumber = set.stringValue(pidsiRevNumber)
+			if t := set.timeValue(pidsiCreateDTM); t != nil {
+				m.Created = t
+			}
+			m.Saved = set.timeValue(pidsiLastSaveDTM)
+			m.Printed = set.timeValue(pidsiLastPrinted)
+			m.PageCount = set.intValue(pidsiPageCount)
+			m.WordCount = set.intValue(pidsiWordCount)
+			m.CharCount = set.intValue(pidsiCharCount)
+		case fmtidDocSummaryInformation:
+			m.Manager = set.stringValue(piddsiManager)
+			m.Company = set.stringValue(piddsiCompany)
+		case fmtidUserDefinedProperties:
+			m.CustomProperties = set.customProperties()
+		}
+	}
+	m.Settings, _ = getSettings(w.table, w.fib)
+	return m
+}
+
+// read the property sets of a stream of the document, using the decrypted copy of the stream if
+// it was in the encrypted summary stream
+func (w *wordFile) propertySets(name string) ([]propertySet, error) {
+	if b, ok := w.summary[name]; ok {
+		return parsePropertySetStream(b)
+	}
+	return getPropertySets(getStream(w.cfb, name, w.path...))
+}
+
+func (s propertySet) stringValue(id uint32) string {
+	v, _ := s.properties[id].(string)
+	return v
+}
+
+func (s propertySet) intValue(id uint32) int {
+	v, _ := s.properties[id].(int64)
+	return int(v)
+}
+
+func (s propertySet) timeValue(id uint32) *time.Time {
+	v, _ := s.properties[id].(*time.Time)
+	return v
+}
+
+// the named properties of the user defined property set, in property identifier order
+func (s propertySet) customProperties() []CustomProperty {
+	var ids []uint32
+	for id := range s.properties {
+		if _, ok := s.dictionary[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var properties []CustomProperty
+	for _, id := range ids {
+		p := CustomProperty{Name: s.dictionary[id], Value: s.properties[id]}
+		switch v := p.Value.(type) {
+		case string:
+			p.Type = PropertyString
+		case int64:
+			p.Type = PropertyInt
+		case bool:
+			p.Type = PropertyBool
+		case float64:
+			p.Type = PropertyFloat
+		case *time.Time:
+			if v == nil {
+				continue
+			}
+			p.Type, p.Value = PropertyTime, *v
+		}
+		properties = append(properties, p)
+	}
+	return properties
+}
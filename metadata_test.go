@@ -0,0 +1,34 @@
+package doc2txt
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseMetadata(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	m, err := ParseMetadata(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+	if m.Title != "Work Experience" || m.Author != "F20 Automation" || m.LastSavedBy != "Rob Archibald" || m.RevisionNumber != "2" ||
+		m.PageCount != 2 || m.WordCount != 105 || m.CharCount != 603 {
+		t.Error("expected summary information", m)
+	}
+	if m.Created == nil || !m.Created.Equal(time.Date(2017, 8, 7, 23, 17, 0, 0, time.UTC)) ||
+		m.Printed == nil || !m.Printed.Equal(time.Date(2017, 5, 31, 23, 3, 0, 0, time.UTC)) || m.Saved == nil {
+		t.Error("expected summary information times", m.Created, m.Saved, m.Printed)
+	}
+}
+
+func TestParseMetadataNoPrinted(t *testing.T) {
+	f, _ := os.Open(`testData/simpleDoc.doc`)
+	m, err := ParseMetadata(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+	if m.Title != "" || m.Author != "Rob Archibald" || m.Printed != nil || m.CharCount != 5 {
+		t.Error("expected summary information without a printed time", m)
+	}
+}
@@ -0,0 +1,746 @@
+package doc2txt
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var errInvalidMTEF = errors.New("invalid MTEF equation")
+
+const (
+	equationNativeName = "Equation Native"
+	cbEqnOLEFileHdr    = 28 // the size of the EQNOLEFILEHDR before the MTEF data
+)
+
+// MTEF record types
+const (
+	mtEnd          = 0
+	mtLine         = 1
+	mtChar         = 2
+	mtTmpl         = 3
+	mtPile         = 4
+	mtMatrix       = 5
+	mtEmbell       = 6
+	mtRuler        = 7
+	mtFont         = 8 // FONT in version 3 and FONT_STYLE_DEF in version 5
+	mtSize         = 9
+	mtFull         = 10
+	mtSubSym       = 14
+	mtColor        = 15
+	mtColorDef     = 16
+	mtFontDef      = 17
+	mtEqnPrefs     = 18
+	mtEncodingDef  = 19
+	mtFuture       = 100
+	mtTypefaceText = 1
+	mtTypefaceFunc = 2
+	mtTypefaceVec  = 7
+)
+
+// MTEF record options, with the values of version 5. The options of version 3 are mapped to these
+const (
+	mtoCharEmbell  = 0x01
+	mtoCharFunc    = 0x02
+	mtoLineNull    = 0x01
+	mtoLineRuler   = 0x02
+	mtoLineLSpace  = 0x04
+	mtoNudge       = 0x08
+	mtoColorCMYK   = 0x01
+	mtoColorName   = 0x04
+	mtoNoMTCode    = 0x20 // characters without an MTCode value
+	mtoEncChar8    = 0x04
+	mtoEncChar16   = 0x10
+	mtVariationBit = 0x80 // a variation of two bytes in version 5
+)
+
+// the LaTeX commands of the MTEF embellishments, with %s for the character they embellish
+var mtefEmbellishments = map[int]string{
+	2: `\dot{%s}`, 3: `\ddot{%s}`, 4: `\dddot{%s}`, 5: `%s'`, 6: `%s''`, 8: `\tilde{%s}`, 9: `\hat{%s}`,
+	10: `\not{%s}`, 11: `\vec{%s}`, 12: `\overleftarrow{%s}`, 13: `\overleftrightarrow{%s}`, 17: `\bar{%s}`,
+	18: `%s'''`,
+}
+
+// the LaTeX of the function names that have a command
+var latexFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true, "arcsin": true,
+	"arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true, "coth": true, "log": true,
+	"ln": true, "lg": true, "exp": true, "lim": true, "max": true, "min": true, "sup": true, "inf": true,
+	"det": true, "dim": true, "ker": true, "deg": true, "gcd": true, "arg": true, "Pr": true,
+}
+
+// read the equation of an Equation Editor 3.0 or MathType object from its Equation Native stream
+// as LaTeX
+func readEquationNative(f *mscfb.File) (string, error) {
+	b, err := readBlock(f, 0, int(f.Size))
+	if err != nil {
+		return "", err
+	}
+	return parseEquationNative(b)
+}
+
+// find the equations of the Equation Editor 3.0 and MathType objects in the document, as the
+// LaTeX between dollar signs to write in place of the characters that anchor them
+func getEquations(w *wordFile, chars []uint16) map[int][]uint16 {
+	chpx, err := getChpxRuns(w.wordDoc, w.table, w.fib)
+	if err != nil {
+		return nil
+	}
+	equations := map[int][]uint16{}
+	pool := append(append([]string{}, w.path...), objectPoolName)
+	for name, cp := range objectAnchors(chars, getCPRuns(w.clx, chpx)) {
+		path := append(append([]string{}, pool...), name)
+		stream := getStream(w.cfb, equationNativeName, path...)
+		if stream == nil {
+			continue
+		}
+		if latex, err := readEquationNative(stream); err == nil && latex != "" {
+			equations[cp] = utf16.Encode([]rune("$" + latex + "$"))
+		}
+	}
+	return equations
+}
+
+// parse an Equation Native stream, which has an EQNOLEFILEHDR and then the equation in MTEF, the
+// format of MathType. Versions 3, of Equation Editor 3.0, and 5, of MathType 5 and later, are read
+func parseEquationNative(b []byte) (string, error) {
+	if len(b) < cbEqnOLEFileHdr {
+		return "", errInvalidMTEF
+	}
+	cbHdr := getInt16(b, 0)
+	if cbHdr < 2 || cbHdr >= len(b) {
+		return "", errInvalidMTEF
+	}
+	return parseMTEF(b[cbHdr:])
+}
+
+// mtefReader reads the records of an MTEF equation as LaTeX
+type mtefReader struct {
+	b       []byte
+	pos     int
+	version int
+}
+
+// parse MTEF data. The header has the version, platform, product and product version and
+// subversion, and version 5 then has the application key and the equation options
+func parseMTEF(b []byte) (string, error) {
+	if len(b) < 5 {
+		return "", errInvalidMTEF
+	}
+	r := &mtefReader{b: b, pos: 5, version: int(b[0])}
+	switch r.version {
+	case 2, 3:
+	case 5:
+		if err := r.skipString(); err != nil {
+			return "", err
+		}
+		if _, err := r.byte(); err != nil {
+			return "", err
+		}
+	default:
+		return "", errInvalidMTEF
+	}
+	latex, err := r.objectList()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(latex), nil
+}
+
+func (r *mtefReader) byte() (int, error) {
+	if r.pos >= len(r.b) {
+		return 0, errInvalidMTEF
+	}
+	r.pos++
+	return int(r.b[r.pos-1]), nil
+}
+
+func (r *mtefReader) uint16() (int, error) {
+	if r.pos+2 > len(r.b) {
+		return 0, errInvalidMTEF
+	}
+	r.pos += 2
+	return getInt16(r.b, r.pos-2), nil
+}
+
+func (r *mtefReader) skip(n int) error {
+	if r.pos+n > len(r.b) {
+		return errInvalidMTEF
+	}
+	r.pos += n
+	return nil
+}
+
+// skip a null-terminated string
+func (r *mtefReader) skipString() error {
+	for {
+		c, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if c == 0 {
+			return nil
+		}
+	}
+}
+
+// read the tag of a record, returning its type and options. In version 3 the options are the
+// high four bits of the tag, and in version 5 they are a byte after the tag for the records that
+// have them
+func (r *mtefReader) tag() (int, int, error) {
+	t, err := r.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+	if r.version < 5 {
+		typ, options := t&0x0F, t>>4
+		if typ == mtChar { // xfAUTO and xfEMBELL
+			options = options&^0x03 | options&0x01<<1 | options&0x02>>1
+		}
+		return typ, options, nil
+	}
+	switch t {
+	case mtLine, mtChar, mtTmpl, mtPile, mtMatrix, mtEmbell, mtColorDef, mtEqnPrefs:
+		options, err := r.byte()
+		return t, options, err
+	}
+	return t, 0, nil
+}
+
+// skip a nudge, which moves an object by two small offsets or, if either is 128, two larger ones
+func (r *mtefReader) skipNudge(options int) error {
+	if options&mtoNudge == 0 {
+		return nil
+	}
+	dx, err := r.byte()
+	if err != nil {
+		return err
+	}
+	dy, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if dx == 128 || dy == 128 {
+		return r.skip(4)
+	}
+	return nil
+}
+
+// read records up to an END record as LaTeX. Consecutive characters in the text and function
+// typefaces are grouped into \text and function names
+func (r *mtefReader) objectList() (string, error) {
+	var parts []string
+	var run []rune
+	runTypeface := 0
+	flush := func() {
+		if len(run) > 0 {
+			parts = append(parts, mtefRun(string(run), runTypeface))
+			run = nil
+		}
+	}
+	for {
+		typ, options, err := r.tag()
+		if err != nil {
+			flush()
+			return latexJoin(parts...), err
+		}
+		if typ == mtEnd {
+			flush()
+			return latexJoin(parts...), nil
+		}
+		if typ == mtChar {
+			c, typeface, latex, err := r.char(options)
+			if err != nil {
+				flush()
+				return latexJoin(parts...), err
+			}
+			if (typeface == mtTypefaceText || typeface == mtTypefaceFunc) && options&mtoCharEmbell == 0 && c != 0 {
+				if typeface != runTypeface {
+					flush()
+				}
+				run = append(run, c)
+				runTypeface = typeface
+				continue
+			}
+			flush()
+			parts = append(parts, latex)
+			continue
+		}
+		flush()
+		latex, err := r.record(typ, options)
+		if err != nil {
+			return latexJoin(parts...), err
+		}
+		parts = append(parts, latex)
+	}
+}
+
+// write a run of characters in the text or function typeface
+func mtefRun(s string, typeface int) string {
+	if typeface == mtTypefaceFunc {
+		if latexFunctions[s] {
+			return `\` + s
+		}
+		return `\operatorname{` + latexText(s) + "}"
+	}
+	return `\text{` + latexText(s) + "}"
+}
+
+// read a record other than a character or the end of a list, returning the LaTeX of the lines,
+// templates, piles and matrices and nothing for the records that only format them
+func (r *mtefReader) record(typ, options int) (string, error) {
+	switch typ {
+	case mtLine:
+		return r.line(options)
+	case mtTmpl:
+		return r.template(options)
+	case mtPile:
+		return r.pile(options)
+	case mtMatrix:
+		return r.matrix(options)
+	case mtEmbell:
+		if err := r.skipNudge(options); err != nil {
+			return "", err
+		}
+		_, err := r.byte()
+		return "", err
+	case mtRuler:
+		return "", r.skipRuler()
+	case mtFont:
+		if r.version < 5 { // the typeface, the style and the name
+			if err := r.skip(2); err != nil {
+				return "", err
+			}
+			return "", r.skipString()
+		}
+		return "", r.skip(2) // the font definition and the style
+	case mtSize:
+		size, err := r.byte()
+		if err != nil {
+			return "", err
+		}
+		switch size {
+		case 101: // an explicit point size
+			return "", r.skip(2)
+		case 100: // a typesize and a change from it
+			return "", r.skip(3)
+		}
+		return "", r.skip(1)
+	case mtColor:
+		return "", r.skip(1)
+	case mtColorDef:
+		n := 6
+		if options&mtoColorCMYK != 0 {
+			n = 8
+		}
+		if err := r.skip(n); err != nil {
+			return "", err
+		}
+		if options&mtoColorName != 0 {
+			return "", r.skipString()
+		}
+		return "", nil
+	case mtFontDef:
+		if err := r.skip(1); err != nil {
+			return "", err
+		}
+		return "", r.skipString()
+	case mtEqnPrefs:
+		return "", r.skipEqnPrefs()
+	case mtEncodingDef:
+		return "", r.skipString()
+	}
+	if typ >= mtFuture {
+		n, err := r.uint16()
+		if err != nil {
+			return "", err
+		}
+		return "", r.skip(n)
+	}
+	if typ >= mtFull && typ <= mtSubSym { // typesizes
+		return "", nil
+	}
+	return "", errInvalidMTEF
+}
+
+// read a character, with its embellishments, returning the character, its typeface and its LaTeX
+func (r *mtefReader) char(options int) (rune, int, string, error) {
+	if err := r.skipNudge(options); err != nil {
+		return 0, 0, "", err
+	}
+	typeface, err := r.byte()
+	if err != nil {
+		return 0, 0, "", err
+	}
+	typeface -= 128
+	var code int
+	if r.version < 5 || options&mtoNoMTCode == 0 {
+		if code, err = r.uint16(); err != nil {
+			return 0, 0, "", err
+		}
+	}
+	if r.version >= 5 { // the position of the character in its font
+		if options&mtoEncChar8 != 0 {
+			err = r.skip(1)
+		} else if options&mtoEncChar16 != 0 {
+			err = r.skip(2)
+		}
+		if err != nil {
+			return 0, 0, "", err
+		}
+	}
+
+	c := mtefCharacter(uint16(code))
+	latex := ""
+	if c != 0 {
+		latex = latexChar(c)
+		if typeface == mtTypefaceVec {
+			latex = `\mathbf{` + latex + "}"
+		}
+	}
+	if options&mtoCharEmbell != 0 {
+		for {
+			typ, embellOptions, err := r.tag()
+			if err != nil {
+				return 0, 0, "", err
+			}
+			if typ == mtEnd {
+				break
+			}
+			if typ != mtEmbell {
+				return 0, 0, "", errInvalidMTEF
+			}
+			if err := r.skipNudge(embellOptions); err != nil {
+				return 0, 0, "", err
+			}
+			embell, err := r.byte()
+			if err != nil {
+				return 0, 0, "", err
+			}
+			if format, ok := mtefEmbellishments[embell]; ok {
+				latex = strings.Replace(format, "%s", latex, 1)
+			}
+		}
+	}
+	return c, typeface, latex, nil
+}
+
+// the Unicode character of an MTCode value. Characters in the Private Use Area of the Symbol font
+// are mapped to Unicode, and the rest of the Private Use Area, which MathType uses for spaces and
+// its own symbols, is left out
+func mtefCharacter(code uint16) rune {
+	if code >= 0xF020 && code <= 0xF0FF {
+		if c, ok := symbolCharacter("Symbol", code); ok {
+			return c
+		}
+	}
+	if code < 0x20 || code >= 0xE000 && code <= 0xF8FF {
+		return 0
+	}
+	return rune(code)
+}
+
+// read a line, which is an object list unless it is null
+func (r *mtefReader) line(options int) (string, error) {
+	if err := r.skipNudge(options); err != nil {
+		return "", err
+	}
+	if options&mtoLineLSpace != 0 { // the line spacing
+		if err := r.skip(2); err != nil {
+			return "", err
+		}
+	}
+	if options&mtoLineRuler != 0 {
+		if err := r.expectRuler(); err != nil {
+			return "", err
+		}
+	}
+	if options&mtoLineNull != 0 {
+		return "", nil
+	}
+	return r.objectList()
+}
+
+// skip the RULER record, with its tag, that follows a line or pile that has one
+func (r *mtefReader) expectRuler() error {
+	typ, _, err := r.tag()
+	if err != nil {
+		return err
+	}
+	if typ != mtRuler {
+		return errInvalidMTEF
+	}
+	return r.skipRuler()
+}
+
+// skip the tab stops of a ruler, each of which has a type and an offset
+func (r *mtefReader) skipRuler() error {
+	n, err := r.byte()
+	if err != nil {
+		return err
+	}
+	return r.skip(n * 3)
+}
+
+// skip the equation preferences, which have arrays of sizes and spaces packed in nibbles and
+// then the styles
+func (r *mtefReader) skipEqnPrefs() error {
+	for i := 0; i < 2; i++ {
+		n, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if err := r.skipNibbles(n); err != nil {
+			return err
+		}
+	}
+	n, err := r.byte()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		font, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if font != 0 {
+			if err := r.skip(1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skip n dimensions packed in nibbles, each of which ends with a nibble of 0xF
+func (r *mtefReader) skipNibbles(n int) error {
+	high := true
+	c := 0
+	for n > 0 {
+		if high {
+			var err error
+			if c, err = r.byte(); err != nil {
+				return err
+			}
+			if c>>4 == 0x0F {
+				n--
+			}
+		} else if c&0x0F == 0x0F {
+			n--
+		}
+		high = !high
+	}
+	return nil
+}
+
+// read a pile, which is a list of lines stacked vertically
+func (r *mtefReader) pile(options int) (string, error) {
+	if err := r.skipNudge(options); err != nil {
+		return "", err
+	}
+	if err := r.skip(2); err != nil { // the horizontal and vertical alignment
+		return "", err
+	}
+	if options&mtoLineRuler != 0 {
+		if err := r.expectRuler(); err != nil {
+			return "", err
+		}
+	}
+	lines, _, err := r.slots()
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 1 {
+		return lines[0], nil
+	}
+	return latexMatrix(lines, 1), nil
+}
+
+// read a matrix, whose lines are its elements by row
+func (r *mtefReader) matrix(options int) (string, error) {
+	if err := r.skipNudge(options); err != nil {
+		return "", err
+	}
+	if err := r.skip(3); err != nil { // the vertical alignment and the justification of rows and columns
+		return "", err
+	}
+	rows, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	columns, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	// the partition line styles, two bits for each row and column partition
+	if err := r.skip((2*(rows+1)+7)/8 + (2*(columns+1)+7)/8); err != nil {
+		return "", err
+	}
+	elements, _, err := r.slots()
+	if err != nil {
+		return "", err
+	}
+	return latexMatrix(elements, columns), nil
+}
+
+// read the objects of a template or pile up to its END record, returning the LaTeX of its lines
+// and piles, which are its slots, and of its characters, such as the sign of an integral
+func (r *mtefReader) slots() ([]string, []rune, error) {
+	var slots []string
+	var chars []rune
+	for {
+		typ, options, err := r.tag()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch typ {
+		case mtEnd:
+			return slots, chars, nil
+		case mtChar:
+			c, _, _, err := r.char(options)
+			if err != nil {
+				return nil, nil, err
+			}
+			chars = append(chars, c)
+		case mtLine, mtPile, mtMatrix, mtTmpl:
+			latex, err := r.record(typ, options)
+			if err != nil {
+				return nil, nil, err
+			}
+			slots = append(slots, latex)
+		default:
+			if _, err := r.record(typ, options); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+}
+
+// read a template, such as a fraction or an integral, with the selector and variation that
+// identify it
+func (r *mtefReader) template(options int) (string, error) {
+	if err := r.skipNudge(options); err != nil {
+		return "", err
+	}
+	selector, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	variation, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	if r.version >= 5 && variation&mtVariationBit != 0 {
+		high, err := r.byte()
+		if err != nil {
+			return "", err
+		}
+		variation = variation&^mtVariationBit | high<<8
+	}
+	if err := r.skip(1); err != nil { // the template options
+		return "", err
+	}
+	slots, chars, err := r.slots()
+	if err != nil {
+		return "", err
+	}
+	return mtefTemplate(selector, variation, slots, chars), nil
+}
+
+// the LaTeX brackets of the fence templates, by selector
+var mtefFences = [][2]string{
+	{`\langle`, `\rangle`}, {"(", ")"}, {`\{`, `\}`}, {"[", "]"}, {"|", "|"}, {`\|`, `\|`},
+	{`\lfloor`, `\rfloor`}, {`\lceil`, `\rceil`}, {"[", "]"},
+}
+
+// the LaTeX commands of the big operator templates, by selector from the integral at 15, for
+// templates without the character of the operator
+var mtefOperators = []string{`\int`, `\sum`, `\prod`, `\coprod`, `\bigcup`, `\bigcap`, `\int`, `\sum`}
+
+// write a template as LaTeX from its slots and characters
+func mtefTemplate(selector, variation int, slots []string, chars []rune) string {
+	slot := func(i int) string {
+		if i < len(slots) {
+			return slots[i]
+		}
+		return ""
+	}
+	switch {
+	case selector < len(mtefFences) || selector == 9: // fences, where 9 is an interval with its own brackets
+		var fence [2]string
+		if selector == 9 {
+			for i := 0; i < 2 && i < len(chars); i++ {
+				if b, ok := latexBrackets[chars[i]]; ok {
+					fence[i] = b[i]
+				} else {
+					fence[i] = latexChar(chars[i])
+				}
+			}
+		} else {
+			fence = mtefFences[selector]
+		}
+		if variation&0x03 != 0 { // the fences that are present
+			if variation&0x01 == 0 {
+				fence[0] = ""
+			}
+			if variation&0x02 == 0 {
+				fence[1] = ""
+			}
+		}
+		return latexFence(fence[0], fence[1], slot(0))
+	case selector == 10: // root
+		if variation&0x01 != 0 {
+			return `\sqrt[` + slot(1) + "]{" + slot(0) + "}"
+		}
+		return `\sqrt{` + slot(0) + "}"
+	case selector == 11: // fraction
+		if variation&0x02 != 0 {
+			return latexJoin(slot(0), "/", slot(1))
+		}
+		return `\frac{` + slot(0) + "}{" + slot(1) + "}"
+	case selector == 12:
+		return `\underline{` + slot(0) + "}"
+	case selector == 13:
+		return `\overline{` + slot(0) + "}"
+	case selector == 14: // arrow with text above and below
+		return `\xrightarrow[` + slot(1) + "]{" + slot(0) + "}"
+	case selector >= 15 && selector <= 22: // integrals and big operators with their limits
+		op := mtefOperators[selector-15]
+		if len(chars) > 0 && chars[0] != 0 {
+			op = latexChar(chars[0])
+		}
+		return latexJoin(latexLimits(op, slot(1), slot(2)), " ", slot(0))
+	case selector == 23: // limit
+		return latexLimits(slot(0), slot(1), slot(2))
+	case selector == 24 || selector == 25: // horizontal brace or bracket with a label
+		if variation&0x01 != 0 {
+			return `\overbrace{` + slot(0) + "}^{" + slot(1) + "}"
+		}
+		return `\underbrace{` + slot(0) + "}_{" + slot(1) + "}"
+	case selector == 26: // long division
+		return latexJoin(slot(1), `\overline{)`+slot(0)+"}")
+	case selector >= 27 && selector <= 29: // subscript and superscript, of the object before
+		sub, sup := slot(0), slot(1)
+		if selector == 28 && len(slots) == 1 {
+			sub, sup = "", slot(0)
+		}
+		return latexLimits("", sub, sup)
+	case selector == 30: // Dirac bra-ket
+		return latexJoin(`\langle `, slot(0), "|", slot(1), ` \rangle`)
+	case selector == 31:
+		return `\overrightarrow{` + slot(0) + "}"
+	case selector == 32:
+		return `\widetilde{` + slot(0) + "}"
+	case selector == 33:
+		return `\widehat{` + slot(0) + "}"
+	case selector == 34:
+		return `\overset{\frown}{` + slot(0) + "}"
+	case selector == 35: // evaluated at
+		return latexFence("", "|", slot(0))
+	case selector == 37:
+		return `\boxed{` + slot(0) + "}"
+	}
+	return latexJoin(slots...)
+}
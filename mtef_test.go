@@ -0,0 +1,122 @@
+package doc2txt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// make an Equation Native stream with an EQNOLEFILEHDR before the MTEF data
+func equationNativeTestStream(mtef []byte) []byte {
+	b := make([]byte, cbEqnOLEFileHdr)
+	b[0] = cbEqnOLEFileHdr
+	copy(b[8:], uint32Bytes(uint32(len(mtef))))
+	return append(b, mtef...)
+}
+
+// x², a fraction, an embellished character, a function and a Greek letter in MTEF version 3
+var mtefVersion3 = []byte{
+	3, 1, 1, 3, 0, // the header
+	0x0A, // FULL
+	0x01, // LINE
+	0x02, 0x83, 'x', 0,
+	0x03, 27 + 1, 0, 0, // TMPL superscript
+	0x11,                           // null LINE
+	0x01, 0x02, 0x88, '2', 0, 0x00, // LINE with 2
+	0x00,
+	0x03, 11, 0, 0, // TMPL fraction
+	0x01, 0x02, 0x83, 'a', 0, 0x00,
+	0x01, 0x02, 0x83, 'b', 0, 0x00,
+	0x00,
+	0x22, 0x83, 'v', 0, 0x06, 17, 0x00, // CHAR with an overbar
+	0x02, 0x82, 's', 0, 0x02, 0x82, 'i', 0, 0x02, 0x82, 'n', 0,
+	0x02, 0x84, 0xB1, 0x03,
+	0x00, 0x00,
+}
+
+// the square root of x, less than or equal to y, in MTEF version 5
+var mtefVersion5 = []byte{
+	5, 1, 0, 6, 9, 'D', 'S', 'M', 'T', '6', 0, 0, // the header
+	19, 'W', 'i', 'n', 'A', 'l', 'l', 0, // ENCODING_DEF
+	17, 1, 'T', 'i', 'm', 'e', 's', 0, // FONT_DEF
+	18, 0, 1, 0x2F, 0, 1, 1, 0, // EQN_PREFS
+	10,   // FULL
+	1, 0, // LINE
+	3, 0, 10, 0, 0, // TMPL root
+	1, 0, 2, 0, 0x83, 'x', 0, 0,
+	0,
+	2, 0x08, 1, 2, 0x86, 0x64, 0x22, // CHAR with a nudge
+	2, 0, 0x83, 'y', 0,
+	0, 0,
+}
+
+func TestParseEquationNative(t *testing.T) {
+	latex, err := parseEquationNative(equationNativeTestStream(mtefVersion3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `x^{2}\frac{a}{b}\bar{v}\sin\alpha`; latex != expected {
+		t.Errorf("expected %s, got %s", expected, latex)
+	}
+
+	latex, err = parseEquationNative(equationNativeTestStream(mtefVersion5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `\sqrt{x}\le y`; latex != expected {
+		t.Errorf("expected %s, got %s", expected, latex)
+	}
+
+	if _, err := parseEquationNative(equationNativeTestStream([]byte{4, 1, 0, 4, 0, 0})); err != errInvalidMTEF {
+		t.Error("expected version 4 to be invalid", err)
+	}
+	if _, err := parseEquationNative(equationNativeTestStream(mtefVersion3)[:20]); err != errInvalidMTEF {
+		t.Error("expected a short header to be invalid", err)
+	}
+	if _, err := parseEquationNative(equationNativeTestStream(mtefVersion3[:20])); err != errInvalidMTEF {
+		t.Error("expected truncated data to be invalid", err)
+	}
+}
+
+func TestMTEFTemplates(t *testing.T) {
+	for _, test := range []struct {
+		selector, variation int
+		slots               []string
+		chars               []rune
+		latex               string
+	}{
+		{1, 3, []string{"x"}, nil, `\left( x \right)`},
+		{4, 1, []string{"x"}, nil, `\left| x \right.`},
+		{9, 3, []string{"0,1"}, []rune{'[', ')'}, `\left[ 0,1 \right)`},
+		{10, 1, []string{"x", "3"}, nil, `\sqrt[3]{x}`},
+		{11, 2, []string{"a", "b"}, nil, `a/b`},
+		{15, 0, []string{"f", "a", "b"}, []rune{0x222E}, `\oint_{a}^{b} f`},
+		{16, 0, []string{"i", "i=1", ""}, nil, `\sum_{i=1} i`},
+		{27, 0, []string{"i", ""}, nil, `_{i}`},
+		{29, 0, []string{"i", "2"}, nil, `_{i}^{2}`},
+	} {
+		if latex := mtefTemplate(test.selector, test.variation, test.slots, test.chars); latex != test.latex {
+			t.Errorf("%d: expected %s, got %s", test.selector, test.latex, latex)
+		}
+	}
+}
+
+func TestEquationObjects(t *testing.T) {
+	b, err := ioutil.ReadFile(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := testCFBEntry{name: objectPoolName, children: []testCFBEntry{
+		{name: "_42", children: []testCFBEntry{
+			{name: "\x01CompObj", data: compObjTestStream("Microsoft Equation 3.0", "DS Equation", "Equation.3")},
+			{name: equationNativeName, data: equationNativeTestStream(mtefVersion5)},
+		}},
+	}}
+	objects, err := ParseEmbeddedObjects(bytes.NewReader(compoundTestFile(append(rootTestEntries(t, b), pool))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].ProgID != "Equation.3" || objects[0].Equation != `\sqrt{x}\le y` {
+		t.Errorf("expected the equation, got %+v", objects)
+	}
+}
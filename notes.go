@@ -0,0 +1,37 @@
+package doc2txt
+
+import "strings"
+
+// find the footnotes and endnotes using the PlcffndRef, PlcffndTxt, PlcfendRef and PlcfendTxt (sections 2.8.16, 2.8.17, 2.8.19 and 2.8.20)
+func (b *docBuilder) notes() error {
+	fc := b.w.fib.fibRgFcLcb
+	var err error
+	b.doc.Footnotes, err = b.readNotes(StoryFootnotes, fc.fcPlcffndRef, fc.lcbPlcffndRef, fc.fcPlcffndTxt, fc.lcbPlcffndTxt)
+	if err != nil {
+		return err
+	}
+	b.doc.Endnotes, err = b.readNotes(StoryEndnotes, fc.fcPlcfendRef, fc.lcbPlcfendRef, fc.fcPlcfendTxt, fc.lcbPlcfendTxt)
+	return err
+}
+
+func (b *docBuilder) readNotes(typ StoryType, fcRef, lcbRef, fcTxt, lcbTxt int) ([]Note, error) {
+	refs, err := getPlc(b.w.table, fcRef, lcbRef, 2)
+	if err != nil {
+		return nil, err
+	}
+	txt, err := getPlc(b.w.table, fcTxt, lcbTxt, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	story := b.story(typ)
+	var notes []Note
+	for i := range refs.aData {
+		n := Note{CP: refs.aCP[i]}
+		if story != nil && i+1 < len(txt.aCP) {
+			n.Text = strings.TrimSpace(storyText(story, story.CP+txt.aCP[i], story.CP+txt.aCP[i+1])) // drop the space after the note reference
+		}
+		notes = append(notes, n)
+	}
+	return notes, nil
+}
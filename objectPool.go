@@ -0,0 +1,225 @@
+package doc2txt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var errInvalidCompObj = errors.New("invalid CompObj stream")
+
+const (
+	objectPoolName = "ObjectPool"
+	compObjName    = "CompObj" // \x01CompObj, without the first character that mscfb leaves out of names
+	cbCompObjHead  = 28
+	nullClassID    = "{00000000-0000-0000-0000-000000000000}"
+)
+
+// the streams that hold the native data of embedded objects, in order of preference. Objects
+// that are stored by their applications as a compound file, such as Word and Excel 97 documents,
+// use the main stream of the application
+var nativeStreams = []string{
+	"Ole10Native", // \x01Ole10Native, with the data of OLE 1.0 objects such as packages
+	"Package",     // Office Open XML documents
+	"CONTENTS",
+	equationNativeName, // Equation Editor 3.0 and MathType
+	"WordDocument",
+	"Workbook",
+	"Book",
+	"PowerPoint Document",
+}
+
+// find the embedded objects in the storages of the ObjectPool storage (section 2.1.4), with the
+// CPs of the characters that anchor them. The text of embedded Word documents is read while
+// depth is less than the depth in the options
+func getEmbeddedObjects(w *wordFile, chars []uint16, runs []cpRun, depth int) []EmbeddedObject {
+	anchors := objectAnchors(chars, runs)
+	pool := append(append([]string{}, w.path...), objectPoolName)
+	var objects []EmbeddedObject
+	for _, f := range w.cfb.File {
+		if !isPath(f.Path, pool...) || !f.FileInfo().IsDir() {
+			continue
+		}
+		path := append(append([]string{}, pool...), f.Name)
+		o := EmbeddedObject{Name: f.Name, CP: -1}
+		if cp, ok := anchors[f.Name]; ok {
+			o.CP = cp
+		}
+		if id := f.ID(); id != nullClassID {
+			o.ClassID = id
+		}
+		for _, stream := range w.cfb.File {
+			if !isPath(stream.Path, path...) {
+				continue
+			}
+			o.Streams = append(o.Streams, stream.Name)
+			if stream.Name == compObjName {
+				o.UserType, o.ProgID, _ = readCompObj(stream)
+			}
+		}
+		o.native = nativeStream(w.cfb, path)
+		if o.native != nil {
+			o.NativeStream = o.native.Name
+		}
+		if o.isPackage() {
+			o.Package, _ = readOle10Native(o.native)
+		}
+		if o.NativeStream == equationNativeName {
+			o.Equation, _ = readEquationNative(o.native)
+		}
+		if o.NativeStream == "WordDocument" && w.options != nil && depth < w.options.depth {
+			o.Text, o.Objects, _ = readEmbeddedDocument(w, path, depth+1)
+		}
+		objects = append(objects, o)
+	}
+	return objects
+}
+
+// read the text of an embedded Word document in the same way as ParseDoc, and the objects that
+// are embedded in it in turn. depth is the depth of the document
+func readEmbeddedDocument(parent *wordFile, path []string, depth int) (string, []EmbeddedObject, error) {
+	w, err := openEmbeddedWordFile(parent.cfb, path, parent.options)
+	if err != nil {
+		return "", nil, err
+	}
+	text, err := getText(w, w.options)
+	if err != nil {
+		return "", nil, err
+	}
+	b, err := ioutil.ReadAll(text)
+	if err != nil {
+		return "", nil, err
+	}
+	chars, err := getCharacters(w.wordDoc, w.clx)
+	if err != nil {
+		return string(b), nil, err
+	}
+	chpx, err := getChpxRuns(w.wordDoc, w.table, w.fib)
+	if err != nil {
+		return string(b), nil, err
+	}
+	return string(b), getEmbeddedObjects(w, chars, getCPRuns(w.clx, chpx), depth), nil
+}
+
+// find the CPs of the characters with sprmCFOle2 and sprmCPicLocation that anchor embedded
+// objects, by the name of the storage of the object, which is the decimal value of the
+// sprmCPicLocation after an underscore (section 2.6.1)
+func objectAnchors(chars []uint16, runs []cpRun) map[string]int {
+	anchors := map[string]int{}
+	for _, run := range runs {
+		c := defaultChp()
+		c.apply(run.grpprl, defaultChp(), nil)
+		if !c.ole2 || !c.hasPicLoc {
+			continue
+		}
+		for cp := run.cpStart; cp < run.cpEnd && cp < len(chars); cp++ {
+			if chars[cp] == 0x01 {
+				anchors["_"+strconv.Itoa(c.picLocation)] = cp
+			}
+		}
+	}
+	return anchors
+}
+
+// the stream of the object in the storage at path with its native data, or nil if it has none
+// of the nativeStreams
+func nativeStream(r *mscfb.Reader, path []string) *mscfb.File {
+	for _, name := range nativeStreams {
+		if stream := getStream(r, name, path...); stream != nil {
+			return stream
+		}
+	}
+	return nil
+}
+
+// whether the path of a file in the compound file is the same as path
+func isPath(p []string, path ...string) bool {
+	if len(p) != len(path) {
+		return false
+	}
+	for i := range p {
+		if p[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// read the user type and the ProgID of an object from its CompObjStream ([MS-OLEDS] section
+// 2.3.8), which has the header, the AnsiUserType, the AnsiClipboardFormat and then the ProgID in
+// Reserved1
+func readCompObj(f *mscfb.File) (string, string, error) {
+	b, err := readBlock(f, 0, int(f.Size))
+	if err != nil {
+		return "", "", err
+	}
+	if len(b) < cbCompObjHead {
+		return "", "", errInvalidCompObj
+	}
+	offset := cbCompObjHead
+	userType, n, err := lengthPrefixedAnsiString(b[offset:])
+	if err != nil {
+		return "", "", err
+	}
+	offset += n
+
+	if offset+4 > len(b) { // ClipboardFormatOrAnsiString (section 2.3.1)
+		return "", "", errInvalidCompObj
+	}
+	switch marker := binary.LittleEndian.Uint32(b[offset:]); marker {
+	case 0:
+		offset += 4
+	case 0xFFFFFFFF, 0xFFFFFFFE: // a standard clipboard format
+		offset += 8
+	default:
+		offset += 4 + int(marker)
+	}
+	if offset > len(b) {
+		return "", "", errInvalidCompObj
+	}
+
+	progID, _, err := lengthPrefixedAnsiString(b[offset:])
+	if err != nil {
+		return "", "", err
+	}
+	return userType, progID, nil
+}
+
+// read a LengthPrefixedAnsiString ([MS-OLEDS] section 2.1.4), whose length includes the
+// terminating null. Returns the string and the number of bytes read
+func lengthPrefixedAnsiString(b []byte) (string, int, error) {
+	if len(b) < 4 {
+		return "", 0, errInvalidCompObj
+	}
+	length := binary.LittleEndian.Uint32(b)
+	if int64(length) > int64(len(b)-4) {
+		return "", 0, errInvalidCompObj
+	}
+	var u []uint16
+	for _, c := range b[4 : 4+length] {
+		if c == 0 {
+			break
+		}
+		u = append(u, ansiToUnicode(c))
+	}
+	return string(utf16.Decode(u)), 4 + int(length), nil
+}
+
+// Native returns a reader over the native data of the object, or nil if the object does not
+// have one of the streams that hold native data
+func (o EmbeddedObject) Native() io.Reader {
+	if o.native == nil {
+		return nil
+	}
+	return io.NewSectionReader(o.native, 0, o.native.Size)
+}
+
+// add the embedded objects of the document
+func (b *docBuilder) objects() {
+	b.doc.Objects = getEmbeddedObjects(b.w, b.text, b.runs, 0)
+}
@@ -0,0 +1,342 @@
+package doc2txt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// a stream, or a storage if it has children, for compoundTestFile
+type testCFBEntry struct {
+	name     string
+	clsid    []byte
+	data     []byte
+	children []testCFBEntry
+}
+
+// build a version 3 compound file with the entries in its root storage. Streams smaller than
+// 4096 bytes are stored in the mini stream
+func compoundTestFile(entries []testCFBEntry) []byte {
+	const (
+		sectorSize = 512
+		endOfChain = 0xFFFFFFFE
+		freeSect   = 0xFFFFFFFF
+		fatSect    = 0xFFFFFFFD
+		noStream   = 0xFFFFFFFF
+	)
+	var sectors []byte
+	var fat []uint32
+	alloc := func(data []byte) uint32 {
+		if len(data) == 0 {
+			return endOfChain
+		}
+		start := uint32(len(fat))
+		n := (len(data) + sectorSize - 1) / sectorSize
+		for i := 0; i < n; i++ {
+			fat = append(fat, uint32(len(fat)+1))
+		}
+		fat[len(fat)-1] = endOfChain
+		padded := make([]byte, n*sectorSize)
+		copy(padded, data)
+		sectors = append(sectors, padded...)
+		return start
+	}
+	var miniStream []byte
+	var miniFat []uint32
+	allocMini := func(data []byte) uint32 {
+		if len(data) == 0 {
+			return endOfChain
+		}
+		start := uint32(len(miniFat))
+		n := (len(data) + 63) / 64
+		for i := 0; i < n; i++ {
+			miniFat = append(miniFat, uint32(len(miniFat)+1))
+		}
+		miniFat[len(miniFat)-1] = endOfChain
+		padded := make([]byte, n*64)
+		copy(padded, data)
+		miniStream = append(miniStream, padded...)
+		return start
+	}
+
+	type dirEntry struct {
+		name               string
+		objectType         byte
+		left, right, child uint32
+		clsid              []byte
+		start              uint32
+		size               int
+	}
+	dir := []*dirEntry{{name: "Root Entry", objectType: 5, left: noStream, right: noStream, child: noStream}}
+	var add func(parent *dirEntry, children []testCFBEntry)
+	add = func(parent *dirEntry, children []testCFBEntry) {
+		var previous *dirEntry
+		for _, c := range children {
+			d := &dirEntry{name: c.name, objectType: 2, left: noStream, right: noStream, child: noStream, clsid: c.clsid,
+				size: len(c.data)}
+			id := uint32(len(dir))
+			dir = append(dir, d)
+			if previous == nil {
+				parent.child = id
+			} else {
+				previous.right = id
+			}
+			previous = d
+			if c.children != nil {
+				d.objectType = 1
+				add(d, c.children)
+			} else if len(c.data) < 4096 {
+				d.start = allocMini(c.data)
+			} else {
+				d.start = alloc(c.data)
+			}
+		}
+	}
+	add(dir[0], entries)
+	dir[0].start, dir[0].size = alloc(miniStream), len(miniStream)
+
+	miniFatStart, miniFatSectors := uint32(endOfChain), 0
+	if len(miniFat) > 0 {
+		b := make([]byte, (len(miniFat)+127)/128*sectorSize)
+		for i := range b {
+			b[i] = 0xFF
+		}
+		for i, next := range miniFat {
+			binary.LittleEndian.PutUint32(b[i*4:], next)
+		}
+		miniFatStart, miniFatSectors = alloc(b), len(b)/sectorSize
+	}
+
+	b := make([]byte, (len(dir)+3)/4*sectorSize)
+	for i, d := range dir {
+		e := b[i*128:]
+		name := utf16.Encode([]rune(d.name))
+		for j, u := range name {
+			binary.LittleEndian.PutUint16(e[j*2:], u)
+		}
+		binary.LittleEndian.PutUint16(e[64:], uint16(len(name)*2+2))
+		e[66], e[67] = d.objectType, 1
+		binary.LittleEndian.PutUint32(e[68:], d.left)
+		binary.LittleEndian.PutUint32(e[72:], d.right)
+		binary.LittleEndian.PutUint32(e[76:], d.child)
+		copy(e[80:96], d.clsid)
+		binary.LittleEndian.PutUint32(e[116:], d.start)
+		binary.LittleEndian.PutUint32(e[120:], uint32(d.size))
+	}
+	for i := len(dir); i < len(b)/128; i++ { // unused entries
+		binary.LittleEndian.PutUint32(b[i*128+68:], noStream)
+		binary.LittleEndian.PutUint32(b[i*128+72:], noStream)
+		binary.LittleEndian.PutUint32(b[i*128+76:], noStream)
+	}
+	dirStart := alloc(b)
+
+	fatSectors := 1
+	for (len(fat)+fatSectors)*4 > fatSectors*sectorSize {
+		fatSectors++
+	}
+	fatStart := len(fat)
+	for i := 0; i < fatSectors; i++ {
+		fat = append(fat, fatSect)
+	}
+	fatBytes := make([]byte, fatSectors*sectorSize)
+	for i := range fatBytes {
+		fatBytes[i] = 0xFF
+	}
+	for i, next := range fat {
+		binary.LittleEndian.PutUint32(fatBytes[i*4:], next)
+	}
+	sectors = append(sectors, fatBytes...)
+
+	header := make([]byte, sectorSize)
+	copy(header, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	binary.LittleEndian.PutUint16(header[24:], 0x3E)
+	binary.LittleEndian.PutUint16(header[26:], 3)
+	binary.LittleEndian.PutUint16(header[28:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[30:], 9)
+	binary.LittleEndian.PutUint16(header[32:], 6)
+	binary.LittleEndian.PutUint32(header[44:], uint32(fatSectors))
+	binary.LittleEndian.PutUint32(header[48:], dirStart)
+	binary.LittleEndian.PutUint32(header[56:], 4096)
+	binary.LittleEndian.PutUint32(header[60:], miniFatStart)
+	binary.LittleEndian.PutUint32(header[64:], uint32(miniFatSectors))
+	binary.LittleEndian.PutUint32(header[68:], endOfChain)
+	for i := 0; i < 109; i++ {
+		difat := uint32(freeSect)
+		if i < fatSectors {
+			difat = uint32(fatStart + i)
+		}
+		binary.LittleEndian.PutUint32(header[76+i*4:], difat)
+	}
+	return append(header, sectors...)
+}
+
+// the streams at the root of a compound file, such as those of a Word document
+func rootTestEntries(t *testing.T, b []byte) []testCFBEntry {
+	r, err := mscfb.New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []testCFBEntry
+	for _, f := range r.File {
+		if len(f.Path) != 0 || f.FileInfo().IsDir() {
+			continue
+		}
+		data, err := readBlock(f, 0, int(f.Size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := f.Name
+		if f.Initial < 0x20 {
+			name = string(rune(f.Initial)) + name
+		}
+		entries = append(entries, testCFBEntry{name: name, data: data})
+	}
+	return entries
+}
+
+// make a CompObjStream with an AnsiUserType, an AnsiClipboardFormat and a ProgID
+func compObjTestStream(userType, clipboardFormat, progID string) []byte {
+	b := make([]byte, cbCompObjHead)
+	for _, s := range []string{userType, clipboardFormat, progID} {
+		b = append(b, uint32Bytes(uint32(len(s)+1))...)
+		b = append(append(b, s...), 0)
+	}
+	return b
+}
+
+// a Word document with an Excel worksheet and a package in its ObjectPool
+func objectPoolTestDoc(t *testing.T) []byte {
+	b, err := ioutil.ReadFile(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excel := []byte{0x20, 0x08, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+	pool := testCFBEntry{name: objectPoolName, children: []testCFBEntry{
+		{name: "_1234", clsid: excel, children: []testCFBEntry{
+			{name: "\x01CompObj", data: compObjTestStream("Microsoft Excel Worksheet", "Biff8", "Excel.Sheet.8")},
+			{name: "\x03ObjInfo", data: make([]byte, 6)},
+			{name: "Workbook", data: []byte("workbook data")},
+		}},
+		{name: "_5678", children: []testCFBEntry{
+			{name: "\x01CompObj", data: compObjTestStream("Package", "Package", "Package")},
+			{name: "\x01Ole10Native", data: ole10NativeTestStream("report.pdf", `C:\Users\me\report.pdf`, []byte("%PDF-1.4"), false)},
+		}},
+	}}
+	return compoundTestFile(append(rootTestEntries(t, b), pool))
+}
+
+func TestParseEmbeddedObjects(t *testing.T) {
+	objects, err := ParseEmbeddedObjects(bytes.NewReader(objectPoolTestDoc(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatal("expected two objects", objects)
+	}
+	expected := []EmbeddedObject{
+		{Name: "_1234", CP: -1, ClassID: "{00020820-0000-0000-C000-000000000046}", ProgID: "Excel.Sheet.8",
+			UserType: "Microsoft Excel Worksheet", Streams: []string{"CompObj", "ObjInfo", "Workbook"}, NativeStream: "Workbook"},
+		{Name: "_5678", CP: -1, ProgID: "Package", UserType: "Package", Streams: []string{"CompObj", "Ole10Native"},
+			NativeStream: "Ole10Native", Package: &Package{FileName: "report.pdf", SourcePath: `C:\Users\me\report.pdf`,
+				Size: 8, Data: []byte("%PDF-1.4")}},
+	}
+	for i, o := range objects {
+		native, _ := ioutil.ReadAll(o.Native())
+		o.native = nil
+		if !reflect.DeepEqual(o, expected[i]) {
+			t.Errorf("%d: expected %+v, got %+v", i, expected[i], o)
+		}
+		if i == 0 && string(native) != "workbook data" || i == 1 && !bytes.HasSuffix(native, []byte("%PDF-1.4")) {
+			t.Errorf("%d: unexpected native data %q", i, native)
+		}
+	}
+}
+
+func TestEmbeddedObjectAnchors(t *testing.T) {
+	w, err := openWordFile(bytes.NewReader(objectPoolTestDoc(t)), &options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chars, err := getCharacters(w.wordDoc, w.clx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chars[3] = 0x01
+	grpprl := []byte{0x55, 0x08, 0x01, 0x0A, 0x08, 0x01, 0x03, 0x6A} // sprmCFSpec, sprmCFOle2 and sprmCPicLocation
+	grpprl = append(grpprl, uint32Bytes(1234)...)
+	runs := []cpRun{{cpStart: 3, cpEnd: 4, grpprl: grpprl}}
+
+	objects := getEmbeddedObjects(w, chars, runs, 0)
+	if len(objects) != 2 || objects[0].CP != 3 || objects[1].CP != -1 {
+		t.Error("expected the worksheet to be anchored at CP 3", objects)
+	}
+}
+
+func TestEmbeddedDocuments(t *testing.T) {
+	simple, err := ioutil.ReadFile(`testData/simpleDoc.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	complicated, err := ioutil.ReadFile(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the simple document embeds the complicated one, which embeds two copies of the simple one.
+	// Their streams are three storages deep, where mscfb can give them the wrong paths
+	inner := append(rootTestEntries(t, complicated), testCFBEntry{name: objectPoolName, children: []testCFBEntry{
+		{name: "_2", children: rootTestEntries(t, simple)},
+		{name: "_3", children: rootTestEntries(t, simple)},
+	}})
+	b := compoundTestFile(append(rootTestEntries(t, simple), testCFBEntry{name: objectPoolName, children: []testCFBEntry{
+		{name: "_1", children: inner},
+	}}))
+	expected := func(b []byte) string {
+		text, err := ParseDoc(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, _ := ioutil.ReadAll(text)
+		return string(s)
+	}
+
+	text, err := ParseDoc(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := ioutil.ReadAll(text); string(s) != expected(simple) {
+		t.Errorf("expected the text of the outer document, got %q", s)
+	}
+
+	objects, err := ParseEmbeddedObjects(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Text != expected(complicated) || len(objects[0].Objects) != 2 {
+		t.Fatal("expected the complicated document", objects)
+	}
+	for _, o := range objects[0].Objects {
+		if o.Text != expected(simple) {
+			t.Errorf("expected the text of the simple document in %s, got %q", o.Name, o.Text)
+		}
+	}
+
+	objects, err = ParseEmbeddedObjects(bytes.NewReader(b), WithEmbeddedDepth(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objects[0].Text == "" || len(objects[0].Objects) != 2 || objects[0].Objects[0].Text != "" {
+		t.Error("expected only the first embedded document to be read", objects)
+	}
+	objects, err = ParseEmbeddedObjects(bytes.NewReader(b), WithEmbeddedDepth(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objects[0].Text != "" || objects[0].Objects != nil {
+		t.Error("expected no embedded documents to be read", objects)
+	}
+}
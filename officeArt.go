@@ -0,0 +1,255 @@
+package doc2txt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	errInvalidOfficeArt = errors.New("invalid OfficeArt record")
+	errUnknownBlip      = errors.New("unsupported OfficeArt BLIP type")
+)
+
+// OfficeArt record types ([MS-ODRAW] section 2.2)
+const (
+	rtDggContainer    = 0xF000
+	rtBStoreContainer = 0xF001
+	rtDgContainer     = 0xF002
+	rtSpContainer     = 0xF004
+	rtFBSE            = 0xF007
+	rtFSP             = 0xF00A
+	rtFOPT            = 0xF00B
+	rtSecondaryFOPT   = 0xF121
+	rtTertiaryFOPT    = 0xF122
+	rtBlipEMF         = 0xF01A
+	rtBlipWMF         = 0xF01B
+	rtBlipPICT        = 0xF01C
+	rtBlipJPEG        = 0xF01D
+	rtBlipPNG         = 0xF01E
+	rtBlipDIB         = 0xF01F
+	rtBlipTIFF        = 0xF029
+	rtBlipJPEG2       = 0xF02A // a JPEG in the CMYK color space
+)
+
+const (
+	recVerContainer         = 0xF // the recVer of records that hold other records
+	cbOfficeArtRecordHeader = 8
+	cbFBSE                  = 36 // the size of an OfficeArtFBSE before its name and BLIP
+	cbMetafileHeader        = 34
+	metafileDeflate         = 0x00 // the compression of a metafile BLIP that is compressed with DEFLATE
+)
+
+// the format and recInstance with one UID of each type of BLIP ([MS-ODRAW] section 2.2.23). The
+// recInstance one higher has a second UID
+var blipTypes = map[int]struct {
+	format      string
+	recInstance int
+}{
+	rtBlipEMF:   {PictureEMF, 0x3D4},
+	rtBlipWMF:   {PictureWMF, 0x216},
+	rtBlipPICT:  {PicturePICT, 0x542},
+	rtBlipJPEG:  {PictureJPEG, 0x46A},
+	rtBlipPNG:   {PicturePNG, 0x6E0},
+	rtBlipDIB:   {PictureDIB, 0x7A8},
+	rtBlipTIFF:  {PictureTIFF, 0x6E4},
+	rtBlipJPEG2: {PictureJPEG, 0x6E2},
+}
+
+// officeArtRecord is an OfficeArt record with its header ([MS-ODRAW] section 2.2.1)
+type officeArtRecord struct {
+	recVer      int
+	recInstance int
+	recType     int
+	data        []byte // the recLen bytes that follow the header
+}
+
+// parse the record at the start of b. Returns the record and its size including the header
+func parseOfficeArtRecord(b []byte) (officeArtRecord, int, error) {
+	if len(b) < cbOfficeArtRecordHeader {
+		return officeArtRecord{}, 0, errInvalidOfficeArt
+	}
+	verInstance := getInt16(b, 0)
+	recLen := getInt(b, 4)
+	if recLen < 0 || recLen > len(b)-cbOfficeArtRecordHeader {
+		return officeArtRecord{}, 0, errInvalidOfficeArt
+	}
+	end := cbOfficeArtRecordHeader + recLen
+	return officeArtRecord{recVer: verInstance & 0x0F, recInstance: verInstance >> 4, recType: getInt16(b, 2),
+		data: b[cbOfficeArtRecordHeader:end]}, end, nil
+}
+
+// parse the records that are one after the other in b, such as the children of a container
+func parseOfficeArtRecords(b []byte) ([]officeArtRecord, error) {
+	var records []officeArtRecord
+	for offset := 0; offset < len(b); {
+		r, n, err := parseOfficeArtRecord(b[offset:])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+		offset += n
+	}
+	return records, nil
+}
+
+// the BLIP embedded in an OfficeArtFBSE ([MS-ODRAW] section 2.2.32), or nil if the BLIP is stored
+// elsewhere
+func (r officeArtRecord) embeddedBlip() ([]byte, error) {
+	if r.recType != rtFBSE || len(r.data) < cbFBSE {
+		return nil, errInvalidOfficeArt
+	}
+	size := getInt(r.data, 20)
+	cbName := int(r.data[33])
+	start := cbFBSE + cbName
+	if size == 0 || start >= len(r.data) {
+		return nil, nil
+	}
+	if start+size > len(r.data) {
+		return nil, errInvalidOfficeArt
+	}
+	return r.data[start : start+size], nil
+}
+
+// parse a BLIP record and return the format and the contents of the image file
+// ([MS-ODRAW] section 2.2.23). Compressed metafiles are decompressed
+func parseBlip(b []byte) (string, []byte, error) {
+	r, _, err := parseOfficeArtRecord(b)
+	if err != nil {
+		return "", nil, err
+	}
+	blipType, ok := blipTypes[r.recType]
+	if !ok {
+		return "", nil, errUnknownBlip
+	}
+	offset := 16 // rgbUid1
+	if r.recInstance == blipType.recInstance+1 {
+		offset += 16 // rgbUid2
+	}
+
+	switch r.recType {
+	case rtBlipEMF, rtBlipWMF, rtBlipPICT: // OfficeArtMetafileHeader (section 2.2.31)
+		if offset+cbMetafileHeader > len(r.data) {
+			return "", nil, errInvalidOfficeArt
+		}
+		header := r.data[offset : offset+cbMetafileHeader]
+		offset += cbMetafileHeader
+		cbSave := getInt(header, 28)
+		if cbSave < 0 || offset+cbSave > len(r.data) {
+			return "", nil, errInvalidOfficeArt
+		}
+		data := r.data[offset : offset+cbSave]
+		if header[32] == metafileDeflate {
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return "", nil, err
+			}
+			if data, err = ioutil.ReadAll(zr); err != nil {
+				return "", nil, err
+			}
+		}
+		return blipType.format, data, nil
+	}
+
+	offset++ // tag
+	if offset > len(r.data) {
+		return "", nil, errInvalidOfficeArt
+	}
+	return blipType.format, r.data[offset:], nil
+}
+
+// the BLIP of an OfficeArtFBSE, which is either embedded in it or stored in the delay stream at
+// foDelay. For Word documents the delay stream is the WordDocument stream
+func (r officeArtRecord) blip(delay *mscfb.File) ([]byte, error) {
+	blip, err := r.embeddedBlip()
+	if err != nil || blip != nil {
+		return blip, err
+	}
+	return readBlock(delay, getInt(r.data, 28), getInt(r.data, 20))
+}
+
+// officeArtProperty is a property from an OfficeArtFOPT ([MS-ODRAW] section 2.2.7). Complex
+// properties have their data, such as the UTF-16 text of string properties, in complex
+type officeArtProperty struct {
+	value   int
+	complex []byte
+}
+
+// parse the properties of an OfficeArtFOPT, OfficeArtSecondaryFOPT or OfficeArtTertiaryFOPT into
+// props, by property ID. recInstance is the number of properties, whose complex data follows
+// them in the same order
+func parseOfficeArtProperties(r officeArtRecord, props map[int]officeArtProperty) error {
+	complexStart := r.recInstance * 6
+	if complexStart > len(r.data) {
+		return errInvalidOfficeArt
+	}
+	complexOffset := complexStart
+	for i := 0; i < r.recInstance; i++ {
+		opid := getInt16(r.data, i*6)
+		p := officeArtProperty{value: getInt(r.data, i*6+2)}
+		if opid&0x8000 != 0 { // fComplex, where the value is the size of the data
+			if p.value < 0 || complexOffset+p.value > len(r.data) {
+				return errInvalidOfficeArt
+			}
+			p.complex = r.data[complexOffset : complexOffset+p.value]
+			complexOffset += p.value
+		}
+		props[opid&0x3FFF] = p
+	}
+	return nil
+}
+
+// the text of a string property, which is a null-terminated UTF-16 string
+func (p officeArtProperty) stringValue() string {
+	return strings.TrimRight(decodeUTF16(p.complex), "\x00")
+}
+
+// officeArtShape is a shape from an OfficeArtSpContainer ([MS-ODRAW] section 2.2.14)
+type officeArtShape struct {
+	spid      int
+	shapeType int // the MSOSPT value of the shape
+	props     map[int]officeArtProperty
+}
+
+// find the shapes in the records and the containers they hold, such as groups
+func findOfficeArtShapes(records []officeArtRecord) ([]officeArtShape, error) {
+	var shapes []officeArtShape
+	for _, r := range records {
+		if r.recVer != recVerContainer {
+			continue
+		}
+		children, err := parseOfficeArtRecords(r.data)
+		if err != nil {
+			return nil, err
+		}
+		if r.recType != rtSpContainer {
+			found, err := findOfficeArtShapes(children)
+			if err != nil {
+				return nil, err
+			}
+			shapes = append(shapes, found...)
+			continue
+		}
+
+		shape := officeArtShape{props: map[int]officeArtProperty{}}
+		for _, child := range children {
+			switch child.recType {
+			case rtFSP: // OfficeArtFSP (section 2.2.40)
+				if len(child.data) < 4 {
+					return nil, errInvalidOfficeArt
+				}
+				shape.spid, shape.shapeType = getInt(child.data, 0), child.recInstance
+			case rtFOPT, rtSecondaryFOPT, rtTertiaryFOPT:
+				if err := parseOfficeArtProperties(child, shape.props); err != nil {
+					return nil, err
+				}
+			}
+		}
+		shapes = append(shapes, shape)
+	}
+	return shapes, nil
+}
@@ -0,0 +1,85 @@
+package doc2txt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// make an OfficeArt record with the given header fields and data
+func officeArtTestRecord(recVer, recInstance, recType int, data []byte) []byte {
+	b := make([]byte, cbOfficeArtRecordHeader, cbOfficeArtRecordHeader+len(data))
+	binary.LittleEndian.PutUint16(b, uint16(recInstance<<4|recVer))
+	binary.LittleEndian.PutUint16(b[2:], uint16(recType))
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(data)))
+	return append(b, data...)
+}
+
+// make a bitmap BLIP record, with a second UID if uid2 is set
+func bitmapTestBlip(recType, recInstance int, uid2 bool, data []byte) []byte {
+	header := make([]byte, 17) // rgbUid1 and tag
+	if uid2 {
+		header = make([]byte, 33)
+	}
+	return officeArtTestRecord(0, recInstance, recType, append(header, data...))
+}
+
+// make a metafile BLIP record, compressing the data if deflate is set
+func metafileTestBlip(recType, recInstance int, deflate bool, data []byte) []byte {
+	stored := data
+	compression := byte(0xFE)
+	if deflate {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		stored, compression = buf.Bytes(), metafileDeflate
+	}
+	header := make([]byte, 16+cbMetafileHeader)
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(header[16+28:], uint32(len(stored)))
+	header[16+32], header[16+33] = compression, 0xFE
+	return officeArtTestRecord(0, recInstance, recType, append(header, stored...))
+}
+
+func testPNG(width, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
+
+func TestParseBlip(t *testing.T) {
+	pngData := testPNG(3, 2)
+	emf := []byte(" EMF metafile records")
+	tests := []struct {
+		blip     []byte
+		format   string
+		expected []byte
+	}{
+		{bitmapTestBlip(rtBlipPNG, 0x6E0, false, pngData), PicturePNG, pngData},
+		{bitmapTestBlip(rtBlipPNG, 0x6E1, true, pngData), PicturePNG, pngData},
+		{bitmapTestBlip(rtBlipJPEG, 0x46A, false, []byte{0xFF, 0xD8}), PictureJPEG, []byte{0xFF, 0xD8}},
+		{bitmapTestBlip(rtBlipDIB, 0x7A8, false, []byte{40, 0, 0, 0}), PictureDIB, []byte{40, 0, 0, 0}},
+		{metafileTestBlip(rtBlipEMF, 0x3D4, true, emf), PictureEMF, emf},
+		{metafileTestBlip(rtBlipWMF, 0x216, false, emf), PictureWMF, emf},
+	}
+	for i, test := range tests {
+		format, data, err := parseBlip(test.blip)
+		if err != nil || format != test.format || !bytes.Equal(data, test.expected) {
+			t.Errorf("%d: expected %s BLIP, got %s %x %v", i, test.format, format, data, err)
+		}
+	}
+
+	if _, _, err := parseBlip(officeArtTestRecord(0, 0, 0xF018, nil)); err != errUnknownBlip {
+		t.Error("expected unknown BLIP type", err)
+	}
+	if _, _, err := parseBlip(tests[0].blip[:20]); err != errInvalidOfficeArt {
+		t.Error("expected truncated BLIP to fail", err)
+	}
+	if _, _, err := parseBlip(officeArtTestRecord(0, 0x3D4, rtBlipEMF, make([]byte, 20))); err != errInvalidOfficeArt {
+		t.Error("expected truncated metafile header to fail", err)
+	}
+}
@@ -0,0 +1,87 @@
+package doc2txt
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var errInvalidOle10Native = errors.New("invalid Ole10Native package")
+
+const (
+	packageProgID  = "Package"
+	packageClassID = "{0003000C-0000-0000-C000-000000000046}"
+	packageEmbed   = 3 // the type of a package that holds the file rather than linking to it
+)
+
+// read the file in the \x01Ole10Native stream of a package made with Packager
+func readOle10Native(f *mscfb.File) (*Package, error) {
+	b, err := readBlock(f, 0, int(f.Size))
+	if err != nil {
+		return nil, err
+	}
+	return parseOle10Native(b)
+}
+
+// parse an Ole10Native stream. The stream has its size, then the label, which is the file name,
+// and the path the file was embedded from as null-terminated ANSI strings. After two bytes of
+// flags and the type, an embedded file has the temporary path the file was copied to and then
+// the file itself, each after its size
+func parseOle10Native(b []byte) (*Package, error) {
+	if len(b) < 6 {
+		return nil, errInvalidOle10Native
+	}
+	if size := int(binary.LittleEndian.Uint32(b)); size+4 < len(b) {
+		b = b[:size+4]
+	}
+	offset := 6 // the size and the type
+	p := &Package{}
+	var err error
+	if p.FileName, offset, err = nullTerminatedAnsiString(b, offset); err != nil {
+		return nil, err
+	}
+	if p.SourcePath, offset, err = nullTerminatedAnsiString(b, offset); err != nil {
+		return nil, err
+	}
+	if offset+4 > len(b) {
+		return nil, errInvalidOle10Native
+	}
+	if getInt16(b, offset+2) != packageEmbed {
+		return p, nil // a link to the file at SourcePath
+	}
+	offset += 4
+
+	for i := 0; i < 2; i++ { // the temporary path and then the file
+		if offset+4 > len(b) {
+			return nil, errInvalidOle10Native
+		}
+		size := binary.LittleEndian.Uint32(b[offset:])
+		offset += 4
+		if int64(size) > int64(len(b)-offset) {
+			return nil, errInvalidOle10Native
+		}
+		p.Data = b[offset : offset+int(size)]
+		offset += int(size)
+	}
+	p.Size = len(p.Data)
+	return p, nil
+}
+
+// read the null-terminated ANSI string at offset. Returns the string and the offset after it
+func nullTerminatedAnsiString(b []byte, offset int) (string, int, error) {
+	var u []uint16
+	for ; offset < len(b); offset++ {
+		if b[offset] == 0 {
+			return string(utf16.Decode(u)), offset + 1, nil
+		}
+		u = append(u, ansiToUnicode(b[offset]))
+	}
+	return "", 0, errInvalidOle10Native
+}
+
+// whether an embedded object is a package made with Packager, whose file is in its Ole10Native stream
+func (o EmbeddedObject) isPackage() bool {
+	return o.NativeStream == "Ole10Native" && (o.ProgID == packageProgID || o.ClassID == packageClassID)
+}
@@ -0,0 +1,50 @@
+package doc2txt
+
+import (
+	"bytes"
+	"testing"
+)
+
+// make an Ole10Native stream for a package, which holds the file unless link is set
+func ole10NativeTestStream(label, source string, data []byte, link bool) []byte {
+	b := []byte{0, 0, 0, 0, 2, 0}
+	b = append(append(b, label...), 0)
+	b = append(append(b, source...), 0)
+	if link {
+		b = append(b, 0, 0, 1, 0)
+	} else {
+		temp := `C:\Temp\` + label + "\x00"
+		b = append(b, 0, 0, packageEmbed, 0)
+		b = append(append(b, uint32Bytes(uint32(len(temp)))...), temp...)
+		b = append(append(b, uint32Bytes(uint32(len(data)))...), data...)
+	}
+	copy(b, uint32Bytes(uint32(len(b)-4)))
+	return b
+}
+
+func TestParseOle10Native(t *testing.T) {
+	data := []byte("%PDF-1.4")
+	p, err := parseOle10Native(ole10NativeTestStream("r\xe9sum\xe9.pdf", "C:\\Users\\me\\r\xe9sum\xe9.pdf", data, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FileName != "r\u00e9sum\u00e9.pdf" || p.SourcePath != "C:\\Users\\me\\r\u00e9sum\u00e9.pdf" || p.Size != len(data) ||
+		!bytes.Equal(p.Data, data) {
+		t.Errorf("expected embedded file, got %+v", p)
+	}
+
+	p, err = parseOle10Native(ole10NativeTestStream("archive.zip", `\\server\share\archive.zip`, nil, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FileName != "archive.zip" || p.SourcePath != `\\server\share\archive.zip` || p.Data != nil {
+		t.Errorf("expected linked file, got %+v", p)
+	}
+
+	b := ole10NativeTestStream("a.zip", `C:\a.zip`, data, false)
+	for _, n := range []int{3, 10, len(b) - 1} {
+		if _, err := parseOle10Native(b[:n]); err != errInvalidOle10Native {
+			t.Errorf("expected %d bytes to be invalid, got %v", n, err)
+		}
+	}
+}
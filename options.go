@@ -0,0 +1,55 @@
+package doc2txt
+
+// Option changes how a document is read
+type Option func(*options)
+
+type options struct {
+	passwords []string
+	special   map[SpecialCharacter]string
+	text      TextOptions
+	depth     int // the depth of embedded Word documents that are read
+}
+
+// the depth of embedded Word documents that are read unless WithEmbeddedDepth changes it
+const defaultEmbeddedDepth = 3
+
+// WithPassword supplies the passwords to try, in order, when a document is encrypted
+func WithPassword(passwords ...string) Option {
+	return func(o *options) {
+		o.passwords = append(o.passwords, passwords...)
+	}
+}
+
+// WithSpecialCharacter sets the text that ParseDoc writes in place of a special character.
+// An empty string leaves the character out
+func WithSpecialCharacter(c SpecialCharacter, text string) Option {
+	return func(o *options) {
+		o.special[c] = text
+	}
+}
+
+// WithTextOptions sets how ParseDoc writes the text
+func WithTextOptions(t TextOptions) Option {
+	return func(o *options) {
+		o.text = t
+	}
+}
+
+// WithEmbeddedDepth sets how deep the text of Word documents embedded in the document, and in
+// the documents embedded in them, is read. Zero leaves embedded documents unread
+func WithEmbeddedDepth(depth int) Option {
+	return func(o *options) {
+		o.depth = depth
+	}
+}
+
+func getOptions(opts []Option) *options {
+	o := &options{special: map[SpecialCharacter]string{}, depth: defaultEmbeddedDepth}
+	for c, text := range defaultSpecialCharacters {
+		o.special[c] = text
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
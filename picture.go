@@ -0,0 +1,147 @@
+package doc2txt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/jpeg" // register the decoders used to find the size of bitmaps
+	_ "image/png"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var errInvalidPicture = errors.New("invalid picture (PICFAndOfficeArtData) structure")
+
+const (
+	cbPicf      = 0x44
+	mmShapeFile = 0x0066 // the picture has a file name after the PICF (section 2.9.158)
+	picmidStart = 28     // the offset of the PICMID in the PICF
+)
+
+// picture is an inline picture from a PICFAndOfficeArtData (section 2.9.192)
+type picture struct {
+	format  string
+	data    []byte
+	dxaGoal int // the size of the picture before scaling, in twips
+	dyaGoal int
+	mx      int // the horizontal and vertical scaling in tenths of a percent
+	my      int
+
+	name        string // the wzName and wzDescription of the shape
+	description string
+}
+
+// read the picture at fc in the Data stream, which is found from the sprmCPicLocation of the
+// picture character
+func getPicture(data *mscfb.File, fc int) (*picture, error) {
+	lcb, err := readBlock(data, fc, 4)
+	if err != nil || len(lcb) < 4 {
+		return nil, errInvalidPicture
+	}
+	b, err := readBlock(data, fc, getInt(lcb, 0))
+	if err != nil {
+		return nil, errInvalidPicture
+	}
+	return parsePicture(b)
+}
+
+// parse a PICFAndOfficeArtData. The picture is an OfficeArtInlineSpContainer, which is a shape
+// followed by the OfficeArtFBSE records that hold the BLIPs ([MS-ODRAW] section 2.2.15)
+func parsePicture(b []byte) (*picture, error) {
+	if len(b) < cbPicf || getInt16(b, 4) != cbPicf {
+		return nil, errInvalidPicture
+	}
+	p := &picture{
+		dxaGoal: int(int16(getInt16(b, picmidStart))),
+		dyaGoal: int(int16(getInt16(b, picmidStart+2))),
+		mx:      getInt16(b, picmidStart+4),
+		my:      getInt16(b, picmidStart+6),
+	}
+	offset := cbPicf
+	if getInt16(b, 6) == mmShapeFile && offset < len(b) {
+		offset += 1 + int(b[offset]) // cchPicName and stPicName
+	}
+
+	for offset < len(b) {
+		r, n, err := parseOfficeArtRecord(b[offset:])
+		if err != nil {
+			return nil, err
+		}
+		offset += n
+		if r.recType == rtSpContainer {
+			shapes, err := findOfficeArtShapes([]officeArtRecord{r})
+			if err != nil {
+				return nil, err
+			}
+			p.name = shapes[0].props[pidWzName].stringValue()
+			p.description = shapes[0].props[pidWzDescription].stringValue()
+		}
+		if r.recType != rtFBSE {
+			continue
+		}
+		blip, err := r.embeddedBlip()
+		if err != nil {
+			return nil, err
+		}
+		if blip == nil {
+			continue
+		}
+		if p.format, p.data, err = parseBlip(blip); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	return nil, errInvalidPicture
+}
+
+// the displayed size of the picture in twips
+func (p *picture) size() (int, int) {
+	width, height := p.dxaGoal, p.dyaGoal
+	if p.mx > 0 {
+		width = width * p.mx / 1000
+	}
+	if p.my > 0 {
+		height = height * p.my / 1000
+	}
+	return width, height
+}
+
+// the size of a bitmap in pixels, or zeros for metafiles and bitmaps that cannot be read
+func pixelSize(format string, data []byte) (int, int) {
+	switch format {
+	case PicturePNG, PictureJPEG:
+		if c, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			return c.Width, c.Height
+		}
+	case PictureDIB:
+		if len(data) < 12 {
+			break
+		}
+		if getInt(data, 0) == 12 { // BITMAPCOREHEADER
+			return getInt16(data, 4), getInt16(data, 6)
+		}
+		height := int32(binary.LittleEndian.Uint32(data[8:]))
+		if height < 0 { // a top-down bitmap
+			height = -height
+		}
+		return int(int32(binary.LittleEndian.Uint32(data[4:]))), int(height)
+	}
+	return 0, 0
+}
+
+// add the picture anchored by the character at cp. Pictures that cannot be read are left out
+// rather than preventing the rest of the document from being read
+func (b *docBuilder) addPicture(cp int, c chp) {
+	if !c.hasPicLoc || c.data || c.ole2 || b.w.data == nil {
+		return
+	}
+	p, err := getPicture(b.w.data, c.picLocation)
+	if err != nil {
+		return
+	}
+	width, height := p.size()
+	pixelWidth, pixelHeight := pixelSize(p.format, p.data)
+	b.doc.Pictures = append(b.doc.Pictures, Picture{CP: cp, Format: p.format, Width: width, Height: height,
+		PixelWidth: pixelWidth, PixelHeight: pixelHeight, Name: p.name, Description: p.description, Data: p.data})
+}
@@ -0,0 +1,115 @@
+package doc2txt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// make a PICFAndOfficeArtData for a picture 2 by 1 inches at 50% width, with the BLIP in an
+// OfficeArtFBSE after an empty shape container. A name is added when mm is MM_SHAPEFILE
+func pictureTestData(mm int, blip []byte) []byte {
+	b := make([]byte, cbPicf)
+	binary.LittleEndian.PutUint16(b[4:], cbPicf)
+	binary.LittleEndian.PutUint16(b[6:], uint16(mm))
+	binary.LittleEndian.PutUint16(b[picmidStart:], 2880)
+	binary.LittleEndian.PutUint16(b[picmidStart+2:], 1440)
+	binary.LittleEndian.PutUint16(b[picmidStart+4:], 500)
+	binary.LittleEndian.PutUint16(b[picmidStart+6:], 1000)
+	if mm == mmShapeFile {
+		b = append(b, 4, 'a', '.', 'p', 'n')
+	}
+	b = append(b, spContainerTestRecord(0x400, 75, foptTestRecord(0, testStringProperty{pidWzName, "Picture 1"},
+		testStringProperty{pidWzDescription, "A grey picture"}))...)
+
+	fbse := make([]byte, cbFBSE)
+	binary.LittleEndian.PutUint32(fbse[20:], uint32(len(blip)))
+	b = append(b, officeArtTestRecord(2, 6, rtFBSE, append(fbse, blip...))...)
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+func TestParsePicture(t *testing.T) {
+	pngData := testPNG(3, 2)
+	for _, mm := range []int{0x64, mmShapeFile} {
+		p, err := parsePicture(pictureTestData(mm, bitmapTestBlip(rtBlipPNG, 0x6E0, false, pngData)))
+		if err != nil || p.format != PicturePNG || !bytes.Equal(p.data, pngData) {
+			t.Fatal("expected PNG picture", p, err)
+		}
+		if p.name != "Picture 1" || p.description != "A grey picture" {
+			t.Error("expected name and description", p.name, p.description)
+		}
+		if width, height := p.size(); width != 1440 || height != 1440 {
+			t.Error("expected scaled size", width, height)
+		}
+		if width, height := pixelSize(p.format, p.data); width != 3 || height != 2 {
+			t.Error("expected size in pixels", width, height)
+		}
+	}
+
+	dib := make([]byte, 40)
+	binary.LittleEndian.PutUint32(dib, 40)
+	binary.LittleEndian.PutUint32(dib[4:], 16)
+	binary.LittleEndian.PutUint32(dib[8:], 0xFFFFFFF8) // -8 for a top-down bitmap
+	if width, height := pixelSize(PictureDIB, dib); width != 16 || height != 8 {
+		t.Error("expected DIB size in pixels", width, height)
+	}
+	if width, height := pixelSize(PictureEMF, dib); width != 0 || height != 0 {
+		t.Error("expected no size in pixels for a metafile", width, height)
+	}
+
+	if _, err := parsePicture(make([]byte, cbPicf)); err != errInvalidPicture {
+		t.Error("expected invalid PICF", err)
+	}
+	b := pictureTestData(0x64, bitmapTestBlip(rtBlipPNG, 0x6E0, false, pngData))
+	if _, err := parsePicture(b[:len(b)-10]); err != errInvalidOfficeArt {
+		t.Error("expected truncated BLIP to fail", err)
+	}
+}
+
+func TestPictures(t *testing.T) {
+	b, err := ioutil.ReadFile(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := &memoryFile{b: b}
+	doc, _ := mscfb.New(mem)
+	wordDoc, _, _ := getWordDocAndTables(doc)
+	wordDoc.WriteAt([]byte{0x01}, 2048) // the first character of the text
+	const fcPicture = 0x400
+	pngData := testPNG(3, 2)
+	getStream(doc, "Data").WriteAt(pictureTestData(0x64, bitmapTestBlip(rtBlipPNG, 0x6E0, false, pngData)), fcPicture)
+
+	w, err := openWordFile(bytes.NewReader(mem.b), &options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpprl := []byte{0x55, 0x08, 0x01} // sprmCFSpec
+	grpprl = append(grpprl, 0x03, 0x6A, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(grpprl[5:], fcPicture)
+	w.clx.rgPrc = [][]byte{grpprl}
+	w.clx.pcdt.PlcPcd.aPcd[0].prm = 1 // Prm1 for the first Prc
+
+	d, err := getDocument(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Pictures) != 1 {
+		t.Fatal("expected one picture", d.Pictures)
+	}
+	if p := d.Pictures[0]; p.CP != 0 || p.Format != PicturePNG || p.Width != 1440 || p.Height != 1440 ||
+		p.PixelWidth != 3 || p.PixelHeight != 2 || p.Description != "A grey picture" || !bytes.Equal(p.Data, pngData) {
+		t.Error("expected picture at the first character", p)
+	}
+
+	data := getStream(doc, "Data")
+	for _, lcb := range []uint32{0xFFFFFFF0, uint32(data.Size - fcPicture + 1), cbPicf - 1} {
+		data.WriteAt(uint32Bytes(lcb), fcPicture)
+		if _, err := getPicture(data, fcPicture); err != errInvalidPicture {
+			t.Error("expected a picture larger than the Data stream to be invalid", lcb, err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package doc2txt
+
+import (
+	"errors"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	errInvalidPlc      = errors.New("invalid PLC structure")
+	errInvalidSttb     = errors.New("invalid STTB structure")
+	errBlockOutOfRange = errors.New("structure is outside its stream")
+)
+
+// plc is a PLC with n+1 CPs followed by n data elements (section 2.2.2)
+type plc struct {
+	aCP   []int
+	aData [][]byte
+}
+
+// read lcb bytes from offset fc in the given stream. A zero lcb means the structure is not present.
+// The range is checked against the size of the stream before anything is allocated, as fc and lcb
+// come from the file
+func readBlock(stream *mscfb.File, fc, lcb int) ([]byte, error) {
+	if stream == nil || lcb <= 0 {
+		return nil, nil
+	}
+	if fc < 0 || int64(lcb) > stream.Size-int64(fc) {
+		return nil, errBlockOutOfRange
+	}
+	b := make([]byte, lcb)
+	if _, err := stream.ReadAt(b, int64(fc)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// parse a PLC whose data elements are cbData bytes each (section 2.2.2)
+func parsePlc(b []byte, cbData int) (*plc, error) {
+	if len(b) == 0 {
+		return &plc{}, nil
+	}
+	n := (len(b) - 4) / (4 + cbData)
+	if n < 0 || n*(4+cbData)+4 != len(b) {
+		return nil, errInvalidPlc
+	}
+
+	p := &plc{aCP: make([]int, n+1), aData: make([][]byte, n)}
+	for i := 0; i <= n; i++ {
+		p.aCP[i] = getInt(b, i*4)
+	}
+	dataStart := (n + 1) * 4
+	for i := 0; i < n; i++ {
+		p.aData[i] = b[dataStart+i*cbData : dataStart+(i+1)*cbData]
+	}
+	return p, nil
+}
+
+// read and parse a PLC from the table stream
+func getPlc(table *mscfb.File, fc, lcb, cbData int) (*plc, error) {
+	b, err := readBlock(table, fc, lcb)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlc(b, cbData)
+}
+
+// parse an STTB string table, returning the strings and their ExtraData (section 2.2.4)
+func parseSttb(b []byte) ([]string, [][]byte, error) {
+	if len(b) == 0 {
+		return nil, nil, nil
+	}
+	if len(b) < 4 {
+		return nil, nil, errInvalidSttb
+	}
+
+	extended := b[0] == 0xFF && b[1] == 0xFF
+	offset := 0
+	if extended {
+		offset = 2
+	}
+	cData := getInt16(b, offset)
+	cbExtra := getInt16(b, offset+2)
+	offset += 4
+
+	strs := make([]string, 0, cData)
+	extras := make([][]byte, 0, cData)
+	for i := 0; i < cData; i++ {
+		var s string
+		if extended {
+			if offset+2 > len(b) {
+				return nil, nil, errInvalidSttb
+			}
+			cch := getInt16(b, offset)
+			offset += 2
+			if offset+cch*2 > len(b) {
+				return nil, nil, errInvalidSttb
+			}
+			s = decodeUTF16(b[offset : offset+cch*2])
+			offset += cch * 2
+		} else {
+			if offset+1 > len(b) {
+				return nil, nil, errInvalidSttb
+			}
+			cch := int(b[offset])
+			offset++
+			if offset+cch > len(b) {
+				return nil, nil, errInvalidSttb
+			}
+			s = decodeANSI(b[offset : offset+cch])
+			offset += cch
+		}
+		if offset+cbExtra > len(b) {
+			return nil, nil, errInvalidSttb
+		}
+		strs = append(strs, s)
+		extras = append(extras, b[offset:offset+cbExtra])
+		offset += cbExtra
+	}
+	return strs, extras, nil
+}
+
+// parse an Xst, returning the string and the number of bytes used (section 2.9.353)
+func parseXst(b []byte) (string, int) {
+	if len(b) < 2 {
+		return "", len(b)
+	}
+	cch := getInt16(b, 0)
+	end := 2 + cch*2
+	if end > len(b) {
+		end = len(b) - len(b)%2
+	}
+	return decodeUTF16(b[2:end]), end
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(getInt16(b, i*2))
+	}
+	return string(utf16.Decode(u))
+}
+
+// decode 8-bit text using the same mapping as compressed text in the piece table (section 2.9.73)
+func decodeANSI(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(ansiToUnicode(c))
+	}
+	return string(r)
+}
@@ -1,11 +1,18 @@
 package doc2txt
 
-/* I don't think I'm going to need this
+import (
+	"sort"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// plcFld holds the field characters of one document part (section 2.8.25)
 type plcFld struct {
 	aCp  []int
 	aFld []fld
 }
 
+// fld is a field character (section 2.9.88)
 type fld struct {
 	fldch     int
 	grffld    int
@@ -18,8 +25,7 @@ func getPlcFld(table *mscfb.File, offset, size int) (*plcFld, error) {
 	if table == nil {
 		return nil, errInvalidArgument
 	}
-	b := make([]byte, size)
-	_, err := table.ReadAt(b, int64(offset))
+	b, err := readBlock(table, offset, size)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +39,36 @@ func getPlcFld(table *mscfb.File, offset, size int) (*plcFld, error) {
 }
 
 func getFld(plc []byte) (*plcFld, error) {
-	return nil, nil
+	p, err := parsePlc(plc, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &plcFld{aCp: p.aCP, aFld: make([]fld, len(p.aData))}
+	for i, data := range p.aData {
+		fldch := int(data[0] & 0x1F) // ch is the low 5 bits of fldch (section 2.9.89)
+		f.aFld[i] = fld{fldch: fldch, grffld: int(data[1])}
+		switch fldch {
+		case 0x13: // grffld is the flt of the field
+			f.aFld[i].fieldtype = getFieldType(data[1])
+		case 0x15: // grffld is a grffldEnd (section 2.9.110)
+			f.aFld[i].fNested = data[1]&0x40 == 0x40
+			f.aFld[i].fHasSep = data[1]&0x80 == 0x80
+		}
+	}
+	return f, nil
+}
+
+// find the type of the field that begins at cp, or an empty string if no field begins there
+func (p *plcFld) fieldType(cp int) string {
+	if p == nil {
+		return ""
+	}
+	i := sort.SearchInts(p.aCp[:len(p.aFld)], cp)
+	if i < len(p.aFld) && p.aCp[i] == cp && p.aFld[i].fldch == 0x13 {
+		return p.aFld[i].fieldtype
+	}
+	return ""
 }
 
 func getFieldType(grffld byte) string {
@@ -214,4 +249,3 @@ func getFieldType(grffld byte) string {
 		return "UNKNOWN"
 	}
 }
-*/
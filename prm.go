@@ -0,0 +1,98 @@
+package doc2txt
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// the sprm for each isprm of a Prm0 (section 2.9.215). All of them take a one byte operand
+var prm0Sprms = map[int]uint16{
+	0x00: 0x2879, // sprmCLbcCRJ
+	0x04: 0x2602, // sprmPIncLvl
+	0x05: sprmPJc,
+	0x07: 0x2405, // sprmPFKeep
+	0x08: 0x2406, // sprmPFKeepFollow
+	0x09: 0x2407, // sprmPFPageBreakBefore
+	0x0C: 0x260A, // sprmPIlvl
+	0x0D: 0x2470, // sprmPFMirrorIndents
+	0x0E: 0x240C, // sprmPFNoLineNumb
+	0x0F: 0x2471, // sprmPTtwo
+	0x18: sprmPFInTable,
+	0x19: sprmPFTtp,
+	0x1D: 0x261B, // sprmPPc
+	0x25: 0x2423, // sprmPWr
+	0x2C: 0x242A, // sprmPFNoAutoHyph
+	0x32: 0x2430, // sprmPFLocked
+	0x33: 0x2431, // sprmPFWidowControl
+	0x35: 0x2433, // sprmPFKinsoku
+	0x36: 0x2434, // sprmPFWordWrap
+	0x37: 0x2435, // sprmPFOverflowPunct
+	0x38: 0x2436, // sprmPFTopLinePunct
+	0x39: 0x2437, // sprmPFAutoSpaceDE
+	0x3A: 0x2438, // sprmPFAutoSpaceDN
+	0x41: sprmCFRMarkDel,
+	0x42: sprmCFRMarkIns,
+	0x43: 0x0802, // sprmCFFldVanish
+	0x47: sprmCFData,
+	0x4B: sprmCFOle2,
+	0x4D: 0x2A0C, // sprmCHighlight
+	0x4E: 0x0858, // sprmCFEmboss
+	0x4F: 0x2859, // sprmCSfxText
+	0x50: 0x0811, // sprmCFWebHidden
+	0x51: 0x0818, // sprmCFSpecVanish
+	0x53: 0x2A33, // sprmCPlain
+	0x55: sprmCFBold,
+	0x56: sprmCFItalic,
+	0x57: sprmCFStrike,
+	0x58: sprmCFOutline,
+	0x59: 0x0839, // sprmCFShadow
+	0x5A: sprmCFSmallCaps,
+	0x5B: sprmCFCaps,
+	0x5C: sprmCFVanish,
+	0x5E: sprmCKul,
+	0x62: sprmCIco,
+	0x68: sprmCIss,
+	0x73: sprmCFDStrike,
+	0x74: 0x0854, // sprmCFImprint
+	0x75: sprmCFSpec,
+	0x76: 0x0856, // sprmCFObj
+	0x78: sprmPOutLvl,
+	0x7B: 0x2A90, // sprmCFSdtVanish
+	0x7C: 0x2A86, // sprmCNeedFontFixup
+	0x7E: 0x2443, // sprmPFNumRMIns
+}
+
+// the properties that the Prm of a Pcd applies to its text, as a grpprl (section 2.9.214). A Prm1
+// refers to a Prc in the Clx, and a Prm0 holds a single sprm and its operand. The Prms of Word 6.0
+// and Word 95 are left out, as their sprms are not the ones that parseGrpprl reads
+func (c *clx) prmGrpprl(prm uint16) []byte {
+	if c.word6 {
+		return nil
+	}
+	if prm&1 == 1 { // fComplex
+		igrpprl := int(prm >> 1)
+		if igrpprl < len(c.rgPrc) {
+			return c.rgPrc[igrpprl]
+		}
+		return nil
+	}
+	isprm, val := int(prm>>1&0x7F), byte(prm>>8)
+	sprm, ok := prm0Sprms[isprm]
+	if !ok || (isprm == 0 && val == 0) { // an isprm and val of zero has no effect
+		return nil
+	}
+	b := make([]byte, 3)
+	binary.LittleEndian.PutUint16(b, sprm)
+	b[2] = val
+	return b
+}
+
+// the properties that the Prm of the Pcd holding the character at cp applies to it
+func (c *clx) prmGrpprlForCP(cp int) []byte {
+	aCP := c.pcdt.PlcPcd.aCP
+	i := sort.Search(len(aCP), func(i int) bool { return aCP[i] > cp }) - 1
+	if i < 0 || i >= len(c.pcdt.PlcPcd.aPcd) {
+		return nil
+	}
+	return c.prmGrpprl(c.pcdt.PlcPcd.aPcd[i].prm)
+}
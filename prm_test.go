@@ -0,0 +1,65 @@
+package doc2txt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrmGrpprl(t *testing.T) {
+	c := &clx{rgPrc: [][]byte{{0x36, 0x08, 0x01}}}
+	tests := []struct {
+		prm      uint16
+		expected []byte
+	}{
+		{0x0000, nil},                           // sprmCLbcCRJ with an operand of zero has no effect
+		{0x55<<1 | 1<<8, []byte{0x35, 0x08, 1}}, // Prm0 sprmCFBold
+		{0x05<<1 | 2<<8, []byte{0x61, 0x24, 2}}, // Prm0 sprmPJc
+		{0x01 << 1, nil},                        // isprm that is not in the table
+		{0<<1 | 1, []byte{0x36, 0x08, 0x01}},    // Prm1 for the first Prc
+		{1<<1 | 1, nil},                         // Prm1 for a Prc that does not exist
+	}
+	for _, test := range tests {
+		if g := c.prmGrpprl(test.prm); !bytes.Equal(g, test.expected) {
+			t.Errorf("expected %x for %#x, got %x", test.expected, test.prm, g)
+		}
+	}
+
+	// the sprms of Word 6.0 and Word 95 are not read, so their Prms are left out
+	c.word6 = true
+	for _, prm := range []uint16{0x55<<1 | 1<<8, 0<<1 | 1} {
+		if g := c.prmGrpprl(prm); g != nil {
+			t.Errorf("expected no properties for the Word 6.0 Prm %#x, got %x", prm, g)
+		}
+	}
+}
+
+func TestPrmRuns(t *testing.T) {
+	// two pieces, where the second is made italic by its Prm1 and the first is made bold by its Prm0
+	c := &clx{rgPrc: [][]byte{{0x36, 0x08, 0x01}}}
+	c.pcdt.PlcPcd.aCP = []int{0, 4, 8}
+	c.pcdt.PlcPcd.aPcd = []pcd{
+		{fc: fcCompressed{fc: 0x1000, fCompressed: true}, prm: 0x55<<1 | 1<<8},
+		{fc: fcCompressed{fc: 0x2000, fCompressed: true}, prm: 1},
+	}
+	chpx := []fkpRun{{fcStart: 0x800, fcEnd: 0x802, grpprl: []byte{0x3A, 0x08, 0x01}}} // small caps
+
+	runs := getCPRuns(c, chpx)
+	if len(runs) != 3 {
+		t.Fatal("expected 3 runs", runs)
+	}
+	var chps []chp
+	for _, run := range runs {
+		props := defaultChp()
+		props.apply(run.grpprl, defaultChp(), nil)
+		chps = append(chps, props)
+	}
+	if !chps[0].bold || !chps[0].smallCaps || !chps[1].bold || chps[1].smallCaps || !chps[2].italic || chps[2].bold {
+		t.Error("expected piece properties to be applied", chps)
+	}
+
+	p := pap{}
+	p.apply(c.prmGrpprl(0x05<<1 | 2<<8))
+	if p.jc != 2 {
+		t.Error("expected paragraph alignment from Prm0", p)
+	}
+}
@@ -0,0 +1,205 @@
+package doc2txt
+
+import (
+	"time"
+)
+
+// chp holds the character properties that are exposed in the document model
+type chp struct {
+	istd         int
+	bold         bool
+	italic       bool
+	strike       bool
+	dStrike      bool
+	outline      bool
+	smallCaps    bool
+	caps         bool
+	hidden       bool
+	special      bool
+	symbol       bool // the character is a symbol from sprmCSymbol
+	symbolFtc    int
+	symbolChar   uint16
+	picLocation  int  // the position of the picture in the Data stream, or of the OLE object
+	hasPicLoc    bool // whether sprmCPicLocation is set
+	data         bool // the picture location is form field data rather than a picture
+	ole2         bool
+	underline    int
+	hps          int
+	iss          int
+	ico          int
+	cv           int
+	hasCv        bool
+	ftc          int
+	rmarkIns     bool
+	rmarkDel     bool
+	ibstRMark    int
+	dttmRMark    uint32
+	ibstRMarkDel int
+	dttmRMarkDel uint32
+}
+
+// pap holds the paragraph properties that are exposed in the document model
+type pap struct {
+	istd     int
+	jc       int
+	fInTable bool
+	fTtp     bool
+	itap     int
+	ilfo     int // the list instance, which is 0 for a paragraph that is not in a list
+	ilvl     int
+	dxaLeft  int
+	dxaLeft1 int
+}
+
+// default character properties (section 2.6.1). Text is 10 point unless a style says otherwise
+func defaultChp() chp {
+	return chp{istd: istdDefaultChar, hps: 20}
+}
+
+// apply a ToggleOperand (section 2.9.327). 0x80 and 0x81 set the value relative to the style
+func toggle(operand int, styleValue bool) bool {
+	switch operand {
+	case 0:
+		return false
+	case 1:
+		return true
+	case 0x80:
+		return styleValue
+	case 0x81:
+		return !styleValue
+	}
+	return styleValue
+}
+
+// apply a grpprl of character sprms to c. base holds the properties from the styles, which toggle
+// operands are relative to. Character styles referenced by sprmCIstd are looked up in s
+func (c *chp) apply(grpprl []byte, base chp, s *stsh) {
+	for _, p := range parseGrpprl(grpprl) {
+		switch p.sprm {
+		case sprmCIstd:
+			c.istd = p.wordOperand()
+			if s != nil {
+				s.applyChpx(c, c.istd, 0)
+			}
+		case sprmCFBold:
+			c.bold = toggle(p.byteOperand(), base.bold)
+		case sprmCFItalic:
+			c.italic = toggle(p.byteOperand(), base.italic)
+		case sprmCFStrike:
+			c.strike = toggle(p.byteOperand(), base.strike)
+		case sprmCFDStrike:
+			c.dStrike = toggle(p.byteOperand(), base.dStrike)
+		case sprmCFOutline:
+			c.outline = toggle(p.byteOperand(), base.outline)
+		case sprmCFSmallCaps:
+			c.smallCaps = toggle(p.byteOperand(), base.smallCaps)
+		case sprmCFCaps:
+			c.caps = toggle(p.byteOperand(), base.caps)
+		case sprmCFVanish:
+			c.hidden = toggle(p.byteOperand(), base.hidden)
+		case sprmCFSpec:
+			c.special = p.byteOperand() == 1
+		case sprmCPicLocation:
+			c.picLocation, c.hasPicLoc = p.longOperand(), true
+		case sprmCFData:
+			c.data = p.byteOperand() != 0
+		case sprmCFOle2:
+			c.ole2 = p.byteOperand() != 0
+		case sprmCSymbol: // a CSymbolOperand of the font and the character code (section 2.9.47)
+			operand := p.longOperand()
+			c.symbol, c.symbolFtc, c.symbolChar = true, operand&0xFFFF, uint16(operand>>16)
+		case sprmCKul:
+			c.underline = p.byteOperand()
+		case sprmCHps:
+			c.hps = p.wordOperand()
+		case sprmCIss:
+			c.iss = p.byteOperand()
+		case sprmCIco:
+			c.ico = p.byteOperand()
+			c.hasCv = false
+		case sprmCCv:
+			c.cv = p.longOperand()
+			c.hasCv = c.cv>>24 == 0 // fAuto is stored in the high byte (section 2.9.43)
+			c.ico = 0
+		case sprmCRgFtc0:
+			c.ftc = p.wordOperand()
+		case sprmCFRMarkIns:
+			c.rmarkIns = toggle(p.byteOperand(), base.rmarkIns)
+		case sprmCFRMarkDel:
+			c.rmarkDel = toggle(p.byteOperand(), base.rmarkDel)
+		case sprmCIbstRMark:
+			c.ibstRMark = p.wordOperand()
+		case sprmCDttmRMark:
+			c.dttmRMark = uint32(p.longOperand())
+		case sprmCIbstRMarkDel:
+			c.ibstRMarkDel = p.wordOperand()
+		case sprmCDttmRMarkDel:
+			c.dttmRMarkDel = uint32(p.longOperand())
+		}
+	}
+}
+
+// apply a grpprl of paragraph sprms to p
+func (p *pap) apply(grpprl []byte) {
+	for _, prl := range parseGrpprl(grpprl) {
+		switch prl.sprm {
+		case sprmPIstd:
+			p.istd = prl.wordOperand()
+		case sprmPJc, sprmPJc80:
+			p.jc = prl.byteOperand()
+		case sprmPFInTable:
+			p.fInTable = prl.byteOperand() != 0
+		case sprmPFTtp:
+			p.fTtp = prl.byteOperand() != 0
+		case sprmPItap:
+			p.itap = prl.longOperand()
+		case sprmPIlfo:
+			p.ilfo = int(int16(prl.wordOperand()))
+		case sprmPIlvl:
+			p.ilvl = prl.byteOperand()
+		case sprmPDxaLeft, sprmPDxaLeft80:
+			p.dxaLeft = int(int16(prl.wordOperand()))
+		case sprmPDxaLeft1, sprmPDxaLeft180:
+			p.dxaLeft1 = int(int16(prl.wordOperand()))
+		}
+	}
+}
+
+// parse a DTTM (section 2.9.65). Returns nil if the date is not set
+func parseDTTM(dttm uint32) *time.Time {
+	mint := int(dttm & 0x3F)
+	hr := int(dttm >> 6 & 0x1F)
+	dom := int(dttm >> 11 & 0x1F)
+	mon := int(dttm >> 16 & 0x0F)
+	yr := int(dttm >> 20 & 0x1FF)
+	if dom == 0 || mon == 0 {
+		return nil
+	}
+	t := time.Date(1900+yr, time.Month(mon), dom, hr, mint, 0, 0, time.UTC)
+	return &t
+}
+
+// the color of the text as an RRGGBB hex string, or an empty string for automatic color
+func (c *chp) color() string {
+	const hex = "0123456789ABCDEF"
+	var rgb int
+	if c.hasCv {
+		rgb = c.cv&0xFF<<16 | c.cv&0xFF00 | c.cv>>16&0xFF // COLORREF is stored as red, green, blue
+	} else if c.ico > 0 && c.ico < len(icoColors) {
+		rgb = icoColors[c.ico]
+	} else {
+		return ""
+	}
+	b := make([]byte, 6)
+	for i := 5; i >= 0; i-- {
+		b[i] = hex[rgb&0xF]
+		rgb >>= 4
+	}
+	return string(b)
+}
+
+// RGB values for the Ico color indexes (section 2.9.119)
+var icoColors = []int{
+	0x000000, 0x000000, 0x0000FF, 0x00FFFF, 0x00FF00, 0xFF00FF, 0xFF0000, 0xFFFF00,
+	0xFFFFFF, 0x000080, 0x008080, 0x008000, 0x800080, 0x800000, 0x808000, 0x808080, 0xC0C0C0,
+}
@@ -0,0 +1,35 @@
+package doc2txt
+
+import (
+	"testing"
+)
+
+func TestChpApply(t *testing.T) {
+	base := defaultChp()
+	base.bold = true
+
+	c := base
+	// sprmCFBold 0x81 (opposite of style), sprmCFItalic 1, sprmCIss superscript, sprmCIco red
+	c.apply([]byte{0x35, 0x08, 0x81, 0x36, 0x08, 0x01, 0x48, 0x2A, 0x01, 0x42, 0x2A, 0x06}, base, nil)
+	if c.bold || !c.italic || c.iss != 1 || c.color() != "FF0000" {
+		t.Error("expected character properties to be applied", c)
+	}
+
+	// sprmCCv with fAuto set is automatic color
+	c.apply([]byte{0x70, 0x68, 0x00, 0x00, 0x00, 0xFF}, base, nil)
+	if c.color() != "" {
+		t.Error("expected automatic color", c.color())
+	}
+	c.apply([]byte{0x70, 0x68, 0x12, 0x34, 0x56, 0x00}, base, nil)
+	if c.color() != "123456" {
+		t.Error("expected COLORREF color", c.color())
+	}
+
+	if d := parseDTTM(0); d != nil {
+		t.Error("expected empty DTTM", d)
+	}
+	// 2017-08-07 16:17
+	if d := parseDTTM(17 | 16<<6 | 7<<11 | 8<<16 | 117<<20); d == nil || d.Year() != 2017 || d.Month() != 8 || d.Day() != 7 || d.Hour() != 16 || d.Minute() != 17 {
+		t.Error("expected valid DTTM", d)
+	}
+}
@@ -0,0 +1,248 @@
+package doc2txt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	errInvalidPropertySet = errors.New("invalid property set stream")
+)
+
+// format identifiers of the property sets in the summary information streams ([MS-OLEPS] section 1.9)
+const (
+	fmtidSummaryInformation    = "F29F85E0-4FF9-1068-AB91-08002B27B3D9"
+	fmtidDocSummaryInformation = "D5CDD502-2E9C-101B-9397-08002B2CF9AE"
+	fmtidUserDefinedProperties = "D5CDD505-2E9C-101B-9397-08002B2CF9AE"
+)
+
+// property types ([MS-OLEPS] section 2.15)
+const (
+	vtI2       = 0x0002
+	vtI4       = 0x0003
+	vtR4       = 0x0004
+	vtR8       = 0x0005
+	vtBool     = 0x000B
+	vtI1       = 0x0010
+	vtUI1      = 0x0011
+	vtUI2      = 0x0012
+	vtUI4      = 0x0013
+	vtI8       = 0x0014
+	vtUI8      = 0x0015
+	vtInt      = 0x0016
+	vtUInt     = 0x0017
+	vtLpstr    = 0x001E
+	vtLpwstr   = 0x001F
+	vtFiletime = 0x0040
+)
+
+const (
+	pidDictionary = 0x00000000
+	pidCodePage   = 0x00000001
+	codePageUTF16 = 1200
+	codePageUTF8  = 65001
+)
+
+// propertySet is a PropertySet with the values of the properties that have a supported type ([MS-OLEPS] section 2.20)
+type propertySet struct {
+	fmtid      string
+	codePage   int
+	properties map[uint32]interface{} // string, int64, float64, bool or *time.Time
+	dictionary map[uint32]string      // property names, only in the user defined property set
+}
+
+// read the property sets from a PropertySetStream. A nil stream has no property sets
+func getPropertySets(stream *mscfb.File) ([]propertySet, error) {
+	if stream == nil {
+		return nil, nil
+	}
+	b, err := readBlock(stream, 0, int(stream.Size))
+	if err != nil {
+		return nil, err
+	}
+	return parsePropertySetStream(b)
+}
+
+// parse a PropertySetStream ([MS-OLEPS] section 2.21)
+func parsePropertySetStream(b []byte) ([]propertySet, error) {
+	if len(b) < 28 || getInt16(b, 0) != 0xFFFE {
+		return nil, errInvalidPropertySet
+	}
+	n := getInt(b, 24)
+	if n > 2 || 28+n*20 > len(b) {
+		return nil, errInvalidPropertySet
+	}
+	sets := make([]propertySet, 0, n)
+	for i := 0; i < n; i++ {
+		fmtid := parseGUID(b[28+i*20:])
+		offset := getInt(b, 28+i*20+16)
+		if offset < 0 || offset+8 > len(b) {
+			return nil, errInvalidPropertySet
+		}
+		set, err := parsePropertySet(b[offset:], fmtid)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// format a GUID as a string ([MS-DTYP] section 2.3.4)
+func parseGUID(b []byte) string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X", getInt(b, 0), getInt16(b, 4), getInt16(b, 6), b[8:10], b[10:16])
+}
+
+// parse a PropertySet ([MS-OLEPS] section 2.20)
+func parsePropertySet(b []byte, fmtid string) (propertySet, error) {
+	set := propertySet{fmtid: fmtid, properties: map[uint32]interface{}{}}
+	size, n := getInt(b, 0), getInt(b, 4)
+	if size < 8 || size > len(b) || 8+n*8 > size {
+		return set, errInvalidPropertySet
+	}
+	b = b[:size]
+
+	// the code page is needed to read the other properties, so find it first
+	offsets := map[uint32]int{}
+	for i := 0; i < n; i++ {
+		offsets[uint32(getInt(b, 8+i*8))] = getInt(b, 8+i*8+4)
+	}
+	if offset, ok := offsets[pidCodePage]; ok && offset+6 <= len(b) {
+		set.codePage = getInt16(b, offset+4)
+	}
+
+	for id, offset := range offsets {
+		if offset < 8 || offset+4 > len(b) {
+			return set, errInvalidPropertySet
+		}
+		switch id {
+		case pidCodePage:
+		case pidDictionary:
+			set.dictionary = parseDictionary(b[offset:], set.codePage)
+		default:
+			if v, ok := parseTypedValue(b[offset:], set.codePage); ok {
+				set.properties[id] = v
+			}
+		}
+	}
+	return set, nil
+}
+
+// parse a TypedPropertyValue, returning false if the type is not supported or the value is truncated ([MS-OLEPS] section 2.15)
+func parseTypedValue(b []byte, codePage int) (interface{}, bool) {
+	vt := getInt16(b, 0)
+	v := b[4:]
+	size := map[int]int{vtI2: 2, vtI4: 4, vtR4: 4, vtR8: 8, vtBool: 2, vtI1: 1, vtUI1: 1, vtUI2: 2,
+		vtUI4: 4, vtI8: 8, vtUI8: 8, vtInt: 4, vtUInt: 4, vtLpstr: 4, vtLpwstr: 4, vtFiletime: 8}[vt]
+	if size == 0 || size > len(v) {
+		return nil, false
+	}
+
+	switch vt {
+	case vtI1:
+		return int64(int8(v[0])), true
+	case vtUI1:
+		return int64(v[0]), true
+	case vtI2:
+		return int64(int16(getInt16(v, 0))), true
+	case vtUI2:
+		return int64(getInt16(v, 0)), true
+	case vtI4, vtInt:
+		return int64(int32(getInt(v, 0))), true
+	case vtUI4, vtUInt:
+		return int64(uint32(getInt(v, 0))), true
+	case vtI8, vtUI8:
+		return int64(getUint64(v, 0)), true
+	case vtR4:
+		return float64(math.Float32frombits(uint32(getInt(v, 0)))), true
+	case vtR8:
+		return math.Float64frombits(getUint64(v, 0)), true
+	case vtBool:
+		return getInt16(v, 0) != 0, true
+	case vtFiletime:
+		return parseFiletime(getUint64(v, 0)), true
+	case vtLpstr:
+		return parseCodePageString(v, codePage)
+	case vtLpwstr:
+		cch := getInt(v, 0)
+		if cch < 0 || 4+cch*2 > len(v) {
+			return nil, false
+		}
+		return trimNull(decodeUTF16(v[4 : 4+cch*2])), true
+	}
+	return nil, false
+}
+
+// parse a CodePageString ([MS-OLEPS] section 2.5)
+func parseCodePageString(b []byte, codePage int) (interface{}, bool) {
+	size := getInt(b, 0)
+	if size < 0 || 4+size > len(b) {
+		return nil, false
+	}
+	s := b[4 : 4+size]
+	switch codePage {
+	case codePageUTF16:
+		return trimNull(decodeUTF16(s)), true
+	case codePageUTF8:
+		return trimNull(string(s)), true
+	}
+	return trimNull(decodeANSI(s)), true
+}
+
+// parse a Dictionary of property names ([MS-OLEPS] section 2.17)
+func parseDictionary(b []byte, codePage int) map[uint32]string {
+	names := map[uint32]string{}
+	n := getInt(b, 0)
+	offset := 4
+	for i := 0; i < n && offset+8 <= len(b); i++ {
+		id := uint32(getInt(b, offset))
+		cch := getInt(b, offset+4) // including the null terminator
+		offset += 8
+		size := cch
+		if codePage == codePageUTF16 {
+			size = cch * 2
+		}
+		if cch < 0 || offset+size > len(b) {
+			break
+		}
+		switch codePage {
+		case codePageUTF16:
+			names[id] = trimNull(decodeUTF16(b[offset : offset+size]))
+			size += (4 - size%4) % 4 // UTF-16 names are padded to a multiple of 4 bytes
+		case codePageUTF8:
+			names[id] = trimNull(string(b[offset : offset+size]))
+		default:
+			names[id] = trimNull(decodeANSI(b[offset : offset+size]))
+		}
+		offset += size
+	}
+	return names
+}
+
+// convert a FILETIME to a time. A zero FILETIME means the time was not recorded ([MS-DTYP] section 2.3.3)
+func parseFiletime(ft uint64) *time.Time {
+	if ft == 0 {
+		return nil
+	}
+	const epochDiff = 116444736000000000 // 100 nanosecond intervals from 1601 to 1970
+	ticks := int64(ft) - epochDiff
+	t := time.Unix(ticks/10000000, (ticks%10000000)*100).UTC()
+	return &t
+}
+
+func trimNull(s string) string {
+	for i, r := range s {
+		if r == 0 {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func getUint64(buf []byte, start int) uint64 {
+	return uint64(getInt(buf, start)) | uint64(getInt(buf, start+4))<<32
+}
@@ -0,0 +1,110 @@
+package doc2txt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTypedValue(t *testing.T) {
+	tests := []struct {
+		b        []byte
+		codePage int
+		expected interface{}
+	}{
+		{[]byte{0x02, 0, 0, 0, 0xFE, 0xFF}, 0, int64(-2)},
+		{[]byte{0x13, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF}, 0, int64(0xFFFFFFFF)},
+		{[]byte{0x0B, 0, 0, 0, 0xFF, 0xFF}, 0, true},
+		{[]byte{0x05, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xF8, 0x3F}, 0, 1.5},
+		{[]byte{0x1E, 0, 0, 0, 4, 0, 0, 0, 'a', 0x93, 'b', 0}, 1252, "a“b"},
+		{[]byte{0x1E, 0, 0, 0, 4, 0, 0, 0, 'a', 0, 'b', 0}, codePageUTF16, "ab"},
+		{[]byte{0x1F, 0, 0, 0, 2, 0, 0, 0, 'a', 0, 0, 0}, 1252, "a"},
+	}
+	for i, test := range tests {
+		if v, ok := parseTypedValue(test.b, test.codePage); !ok || v != test.expected {
+			t.Error("expected value", i, test.expected, v)
+		}
+	}
+	if _, ok := parseTypedValue([]byte{0x1E, 0, 0, 0, 9, 0, 0, 0, 'a'}, 1252); ok {
+		t.Error("expected truncated string to fail")
+	}
+	if _, ok := parseTypedValue([]byte{0x41, 0, 0, 0, 0, 0, 0, 0}, 1252); ok {
+		t.Error("expected unsupported type to be skipped")
+	}
+}
+
+func TestParseFiletime(t *testing.T) {
+	if parseFiletime(0) != nil {
+		t.Error("expected zero FILETIME to be unset")
+	}
+	if ft := parseFiletime(131466214200000000); ft == nil || !ft.Equal(time.Date(2017, 8, 7, 23, 17, 0, 0, time.UTC)) {
+		t.Error("expected FILETIME to convert", ft)
+	}
+}
+
+func TestParsePropertySetStreamInvalid(t *testing.T) {
+	if _, err := parsePropertySetStream([]byte{0xFE, 0xFF}); err != errInvalidPropertySet {
+		t.Error("expected short stream to fail", err)
+	}
+}
+
+// build a property set stream with one property set from the given properties and their values
+func buildPropertySetStream(fmtid []byte, properties map[uint32][]byte) []byte {
+	le := func(v int) []byte { return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)} }
+	var ids []uint32
+	for id := range properties {
+		ids = append(ids, id)
+	}
+	header := append([]byte{0xFE, 0xFF, 0, 0}, make([]byte, 20)...)
+	header = append(append(append(header, le(1)...), fmtid...), le(48)...)
+
+	offset := 8 + len(ids)*8
+	var index, values []byte
+	for _, id := range ids {
+		index = append(append(index, le(int(id))...), le(offset+len(values))...)
+		values = append(values, properties[id]...)
+	}
+	set := append(append(le(offset+len(values)), le(len(ids))...), index...)
+	return append(header, append(set, values...)...)
+}
+
+func TestCustomProperties(t *testing.T) {
+	fmtid := []byte{0x05, 0xD5, 0xCD, 0xD5, 0x9C, 0x2E, 0x1B, 0x10, 0x93, 0x97, 0x08, 0x00, 0x2B, 0x2C, 0xF9, 0xAE}
+	b := buildPropertySetStream(fmtid, map[uint32][]byte{
+		pidCodePage: {0x02, 0, 0, 0, 0xE4, 0x04, 0, 0},
+		pidDictionary: {5, 0, 0, 0,
+			2, 0, 0, 0, 5, 0, 0, 0, 'C', 'a', 's', 'e', 0,
+			3, 0, 0, 0, 7, 0, 0, 0, 'C', 'l', 'i', 'e', 'n', 't', 0,
+			4, 0, 0, 0, 6, 0, 0, 0, 'F', 'i', 'n', 'a', 'l', 0,
+			5, 0, 0, 0, 4, 0, 0, 0, 'D', 'u', 'e', 0,
+			6, 0, 0, 0, 5, 0, 0, 0, 'R', 'a', 't', 'e', 0, 0, 0, 0},
+		2: {0x1E, 0, 0, 0, 4, 0, 0, 0, 'A', '-', '1', 0},
+		3: {0x03, 0, 0, 0, 0x39, 0x30, 0, 0},
+		4: {0x0B, 0, 0, 0, 0xFF, 0xFF, 0, 0},
+		5: {0x40, 0, 0, 0, 0x00, 0x3E, 0x5B, 0x45, 0xD3, 0x0F, 0xD3, 0x01},
+		6: {0x05, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xF8, 0x3F},
+		7: {0x03, 0, 0, 0, 1, 0, 0, 0}, // not in the dictionary
+	})
+	sets, err := parsePropertySetStream(b)
+	if err != nil || len(sets) != 1 || sets[0].fmtid != fmtidUserDefinedProperties {
+		t.Fatal("expected user defined property set", sets, err)
+	}
+
+	props := sets[0].customProperties()
+	expected := []CustomProperty{
+		{"Case", PropertyString, "A-1"},
+		{"Client", PropertyInt, int64(12345)},
+		{"Final", PropertyBool, true},
+		{"Due", PropertyTime, time.Date(2017, 8, 7, 23, 17, 0, 0, time.UTC)},
+		{"Rate", PropertyFloat, 1.5},
+	}
+	if len(props) != len(expected) {
+		t.Fatal("expected custom properties", props)
+	}
+	for i, p := range props {
+		e := expected[i]
+		if p.Name != e.Name || p.Type != e.Type || (p.Type != PropertyTime && p.Value != e.Value) ||
+			(p.Type == PropertyTime && !p.Value.(time.Time).Equal(e.Value.(time.Time))) {
+			t.Error("expected custom property", e, p)
+		}
+	}
+}
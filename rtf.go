@@ -0,0 +1,261 @@
+package doc2txt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+)
+
+const rtfTableWidth = 9360 // the width of a table in twips, 6.5 inches
+
+// WriteRTF writes the main story of the document as RTF, with its fonts,
+// character formatting, paragraph alignment and tables
+func (d *Document) WriteRTF(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	r := newRTFWriter(d, bw)
+	r.document()
+	return bw.Flush()
+}
+
+// rtfWriter converts the document model to RTF
+type rtfWriter struct {
+	d      *Document
+	w      *bufio.Writer
+	fonts  map[string]int // font name to index in the font table
+	font   []string       // the font table, in order
+	colors map[string]int // RRGGBB to index in the color table
+	color  []string       // the color table, without the automatic color
+}
+
+func newRTFWriter(d *Document, w *bufio.Writer) *rtfWriter {
+	r := &rtfWriter{d: d, w: w, fonts: map[string]int{}, colors: map[string]int{}}
+	addFont := func(name string) {
+		if _, ok := r.fonts[name]; !ok && name != "" {
+			r.fonts[name] = len(r.font)
+			r.font = append(r.font, name)
+		}
+	}
+	for _, f := range d.Fonts {
+		addFont(f.Name)
+	}
+	r.eachRun(func(run *Run) {
+		addFont(run.Font)
+		if _, ok := r.colors[run.Color]; !ok && run.Color != "" {
+			r.color = append(r.color, run.Color)
+			r.colors[run.Color] = len(r.color) // index 0 is the automatic color
+		}
+	})
+	return r
+}
+
+// call fn for each run of the main story
+func (r *rtfWriter) eachRun(fn func(*Run)) {
+	paragraph := func(p *Paragraph) {
+		for i := range p.Runs {
+			fn(&p.Runs[i])
+		}
+	}
+	for _, story := range r.d.Stories {
+		if story.Type != StoryMain {
+			continue
+		}
+		for _, section := range story.Sections {
+			for _, block := range section.Blocks {
+				if block.Paragraph != nil {
+					paragraph(block.Paragraph)
+					continue
+				}
+				for _, row := range block.Table.Rows {
+					for _, cell := range row.Cells {
+						for i := range cell.Paragraphs {
+							paragraph(&cell.Paragraphs[i])
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (r *rtfWriter) document() {
+	r.w.WriteString(`{\rtf1\ansi\ansicpg1252\deff0`)
+	r.fontTable()
+	r.colorTable()
+	r.w.WriteString("\n")
+	for _, story := range r.d.Stories {
+		if story.Type != StoryMain {
+			continue
+		}
+		for i, section := range story.Sections {
+			if i > 0 {
+				r.w.WriteString(`\sect` + "\n")
+			}
+			for _, block := range section.Blocks {
+				if block.Table != nil {
+					r.table(block.Table)
+				} else {
+					r.paragraph(block.Paragraph, false)
+					r.w.WriteString(`\par` + "\n")
+				}
+			}
+		}
+	}
+	r.w.WriteString("}\n")
+}
+
+func (r *rtfWriter) fontTable() {
+	names := r.font
+	if len(names) == 0 {
+		names = []string{"Times New Roman"}
+	}
+	fonts := map[string]Font{}
+	for _, f := range r.d.Fonts {
+		if _, ok := fonts[f.Name]; !ok {
+			fonts[f.Name] = f
+		}
+	}
+	r.w.WriteString(`{\fonttbl`)
+	for i, name := range names {
+		fmt.Fprintf(r.w, `{\f%d`, i)
+		f, ok := fonts[name]
+		if !ok {
+			fmt.Fprintf(r.w, `\fnil %s;}`, rtfEscape(name))
+			continue
+		}
+		fmt.Fprintf(r.w, `%s\fcharset%d`, rtfFontFamily(f.Family), f.Charset)
+		if prq, ok := rtfFontPitches[f.Pitch]; ok {
+			fmt.Fprintf(r.w, `\fprq%d`, prq)
+		}
+		fmt.Fprintf(r.w, ` %s;}`, rtfEscape(name))
+	}
+	r.w.WriteString("}")
+}
+
+// the RTF pitch of each font pitch
+var rtfFontPitches = map[string]int{"fixed": 1, "variable": 2}
+
+// the RTF control word for a font family
+func rtfFontFamily(family string) string {
+	switch family {
+	case "roman", "swiss", "modern", "script":
+		return `\f` + family
+	case "decorative":
+		return `\fdecor`
+	}
+	return `\fnil`
+}
+
+func (r *rtfWriter) colorTable() {
+	if len(r.color) == 0 {
+		return
+	}
+	r.w.WriteString(`{\colortbl;`)
+	for _, c := range r.color {
+		rgb, err := strconv.ParseUint(c, 16, 32)
+		if err != nil {
+			rgb = 0
+		}
+		fmt.Fprintf(r.w, `\red%d\green%d\blue%d;`, rgb>>16, (rgb>>8)&0xFF, rgb&0xFF)
+	}
+	r.w.WriteString("}")
+}
+
+// write the rows of a table. Cell widths are not in the document model, so the cells of each row are equal
+func (r *rtfWriter) table(t *Table) {
+	for _, row := range t.Rows {
+		r.w.WriteString(`\trowd\trgaph108`)
+		for i := range row.Cells {
+			fmt.Fprintf(r.w, `\cellx%d`, rtfTableWidth*(i+1)/len(row.Cells))
+		}
+		r.w.WriteString("\n")
+		for _, cell := range row.Cells {
+			for i := range cell.Paragraphs {
+				if i > 0 {
+					r.w.WriteString(`\par`)
+				}
+				r.paragraph(&cell.Paragraphs[i], true)
+			}
+			r.w.WriteString(`\cell` + "\n")
+		}
+		r.w.WriteString(`\row` + "\n")
+	}
+}
+
+func (r *rtfWriter) paragraph(p *Paragraph, inTable bool) {
+	r.w.WriteString(`\pard\plain`)
+	if inTable {
+		r.w.WriteString(`\intbl`)
+	}
+	switch p.Alignment {
+	case "center":
+		r.w.WriteString(`\qc`)
+	case "right":
+		r.w.WriteString(`\qr`)
+	case "justify":
+		r.w.WriteString(`\qj`)
+	case "distribute":
+		r.w.WriteString(`\qd`)
+	}
+	for _, run := range p.Runs {
+		if run.Deleted {
+			continue
+		}
+		r.w.WriteString(`{`)
+		r.runProperties(run)
+		r.w.WriteString(" ")
+		r.w.WriteString(rtfEscape(run.Text))
+		r.w.WriteString(`}`)
+	}
+}
+
+func (r *rtfWriter) runProperties(run Run) {
+	if i, ok := r.fonts[run.Font]; ok {
+		fmt.Fprintf(r.w, `\f%d`, i)
+	}
+	if run.Size > 0 {
+		fmt.Fprintf(r.w, `\fs%d`, int(run.Size*2)) // in half points
+	}
+	if i, ok := r.colors[run.Color]; ok {
+		fmt.Fprintf(r.w, `\cf%d`, i)
+	}
+	flags := []struct {
+		on   bool
+		word string
+	}{
+		{run.Bold, `\b`}, {run.Italic, `\i`}, {run.Underline, `\ul`}, {run.Strike, `\strike`},
+		{run.SmallCaps, `\scaps`}, {run.Caps, `\caps`}, {run.Hidden, `\v`},
+		{run.VerticalAlign == "superscript", `\super`}, {run.VerticalAlign == "subscript", `\sub`},
+	}
+	for _, flag := range flags {
+		if flag.on {
+			r.w.WriteString(flag.word)
+		}
+	}
+}
+
+// escape text for RTF. Characters outside ASCII are written as UTF-16 code units with \u
+func rtfEscape(s string) string {
+	var out []byte
+	for _, c := range s {
+		switch {
+		case c == '\\' || c == '{' || c == '}':
+			out = append(out, '\\', byte(c))
+		case c == '\t':
+			out = append(out, `\tab `...)
+		case c == '\n':
+			out = append(out, `\line `...)
+		case c == '\f':
+			out = append(out, `\page `...)
+		case c >= 0x20 && c < 0x80:
+			out = append(out, byte(c))
+		case c < 0x20:
+		default:
+			for _, u := range utf16.Encode([]rune{c}) {
+				out = append(out, fmt.Sprintf(`\u%d?`, int16(u))...)
+			}
+		}
+	}
+	return string(out)
+}
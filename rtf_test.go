@@ -0,0 +1,53 @@
+package doc2txt
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteRTF(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	d, err := ParseDocument(f)
+	if err != nil {
+		t.Fatal("expected successful parse", err)
+	}
+	var buf bytes.Buffer
+	if err := d.WriteRTF(&buf); err != nil {
+		t.Fatal("expected to write RTF", err)
+	}
+	rtf := buf.String()
+	if !strings.HasPrefix(rtf, `{\rtf1\ansi\ansicpg1252\deff0{\fonttbl{\f0\froman\fcharset0\fprq2 Times New Roman;}{\f1\froman\fcharset2\fprq2 Symbol;}{\f2\fswiss\fcharset0\fprq2 Arial;}`) ||
+		!strings.HasSuffix(rtf, "}\n") || strings.Count(rtf, "{") != strings.Count(rtf, "}") {
+		t.Error("expected RTF header and balanced groups", rtf)
+	}
+	for _, s := range []string{
+		`{\colortbl;\red0\green0\blue128;`,
+		`\pard\plain\qc{\f2\fs60\cf1\b Name Here in Big}\par`,
+		`\pard\plain{\f2\fs24\i Italics}\par`,
+		`\trowd\trgaph108\cellx4680\cellx9360` + "\n" + `\pard\plain\intbl{\f2\fs20\b Some}\cell`,
+	} {
+		if !strings.Contains(rtf, s) {
+			t.Error("expected RTF to contain", s)
+		}
+	}
+}
+
+func TestRTFFontTable(t *testing.T) {
+	d := &Document{Fonts: []Font{{Name: "Wingdings", Charset: symbolCharset, Pitch: "variable"}}}
+	d.Stories = []Story{{Type: StoryMain, Sections: []Section{{Blocks: []Block{{Paragraph: &Paragraph{Runs: []Run{{Font: "Other", Text: "x"}}}}}}}}}
+	var buf bytes.Buffer
+	if err := d.WriteRTF(&buf); err != nil {
+		t.Fatal("expected to write RTF", err)
+	}
+	if s := `{\fonttbl{\f0\fnil\fcharset2\fprq2 Wingdings;}{\f1\fnil Other;}}`; !strings.Contains(buf.String(), s) {
+		t.Error("expected font table", buf.String())
+	}
+}
+
+func TestRTFEscape(t *testing.T) {
+	if s := rtfEscape("a{b}\\c\td\neé—\U0001F600\x01"); s != `a\{b\}\\c\tab d\line e\u233?\u8212?\u-10179?\u-8704?` {
+		t.Error("expected escaped text", s)
+	}
+}
@@ -0,0 +1,34 @@
+package doc2txt
+
+// SpecialCharacter is a Word character with a special meaning in the text (section 2.4.1 and
+// section 2.6.1 sprmCFSpec). Its value is the character code
+type SpecialCharacter uint16
+
+// The special characters whose text can be set with WithSpecialCharacter
+const (
+	SpecialPicture           SpecialCharacter = 0x01 // the anchor of an inline picture
+	SpecialFootnoteNumber    SpecialCharacter = 0x02 // an automatically numbered footnote or endnote reference
+	SpecialAnnotation        SpecialCharacter = 0x05 // the reference to a comment
+	SpecialDrawnObject       SpecialCharacter = 0x08 // the anchor of a shape or floating picture
+	SpecialLineBreak         SpecialCharacter = 0x0B
+	SpecialPageBreak         SpecialCharacter = 0x0C // a page break, or a section break which also ends the paragraph
+	SpecialColumnBreak       SpecialCharacter = 0x0E
+	SpecialNonBreakingHyphen SpecialCharacter = 0x1E
+	SpecialOptionalHyphen    SpecialCharacter = 0x1F
+	SpecialNonBreakingSpace  SpecialCharacter = 0xA0
+)
+
+// the text that ParseDoc writes for each special character by default. Breaks keep the text
+// apart and the anchors of objects, which have no text, are left out
+var defaultSpecialCharacters = map[SpecialCharacter]string{
+	SpecialPicture:           "",
+	SpecialFootnoteNumber:    "",
+	SpecialAnnotation:        "",
+	SpecialDrawnObject:       "",
+	SpecialLineBreak:         "\n",
+	SpecialPageBreak:         "\f",
+	SpecialColumnBreak:       "\n",
+	SpecialNonBreakingHyphen: "\u2011",
+	SpecialOptionalHyphen:    "",
+	SpecialNonBreakingSpace:  "\u00A0",
+}
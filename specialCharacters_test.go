@@ -0,0 +1,41 @@
+package doc2txt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestSpecialCharacters(t *testing.T) {
+	chars := utf16.Encode([]rune("a\x01b\x02c\x05d\x08e\x0Bf\x0Cg\x0Eh\x1Ei\x1Fj\u00A0k\r"))
+	var buf bytes.Buffer
+	translateText(chars, nil, getOptions(nil).special, &buf)
+	if s := buf.String(); s != "abcde\nf\fg\nh\u2011ij\u00A0k\r" {
+		t.Errorf("expected default special characters, got %q", s)
+	}
+
+	buf.Reset()
+	o := getOptions([]Option{
+		WithSpecialCharacter(SpecialPicture, "[picture]"),
+		WithSpecialCharacter(SpecialPageBreak, "\r"),
+		WithSpecialCharacter(SpecialNonBreakingHyphen, "-"),
+		WithSpecialCharacter(SpecialNonBreakingSpace, " "),
+		WithSpecialCharacter(SpecialLineBreak, ""),
+	})
+	translateText(chars, nil, o.special, &buf)
+	if s := buf.String(); s != "a[picture]bcdef\rg\nh-ij k\r" {
+		t.Errorf("expected special characters from options, got %q", s)
+	}
+}
+
+func TestParseDocSpecialCharacters(t *testing.T) {
+	doc := legacyTestDoc(FormatWord2, "page\x0Cbreak\x1Ehyphen\r")
+	r, err := ParseDoc(bytes.NewReader(doc), WithSpecialCharacter(SpecialNonBreakingHyphen, "-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := ioutil.ReadAll(r); string(s) != "page\fbreak-hyphen\r" {
+		t.Errorf("expected page break and hyphen, got %q", s)
+	}
+}
@@ -0,0 +1,148 @@
+package doc2txt
+
+import (
+	"encoding/binary"
+)
+
+// sprm values used when applying properties (section 2.6)
+const (
+	sprmCFRMarkDel    = 0x0800
+	sprmCFRMarkIns    = 0x0801
+	sprmCIbstRMark    = 0x4804
+	sprmCDttmRMark    = 0x6805
+	sprmCFData        = 0x0806
+	sprmCFOle2        = 0x080A
+	sprmCPicLocation  = 0x6A03
+	sprmCSymbol       = 0x6A09
+	sprmCFSpec        = 0x0855
+	sprmCIstd         = 0x4A30
+	sprmCFBold        = 0x0835
+	sprmCFItalic      = 0x0836
+	sprmCFStrike      = 0x0837
+	sprmCFOutline     = 0x0838
+	sprmCFSmallCaps   = 0x083A
+	sprmCFCaps        = 0x083B
+	sprmCFVanish      = 0x083C
+	sprmCKul          = 0x2A3E
+	sprmCIco          = 0x2A42
+	sprmCHps          = 0x4A43
+	sprmCIss          = 0x2A48
+	sprmCRgFtc0       = 0x4A4F
+	sprmCFDStrike     = 0x2A53
+	sprmCIbstRMarkDel = 0x4863
+	sprmCDttmRMarkDel = 0x6864
+	sprmCCv           = 0x6870
+
+	sprmPIstd       = 0x4600
+	sprmPJc80       = 0x2403
+	sprmPIlvl       = 0x260A
+	sprmPIlfo       = 0x460B
+	sprmPDxaLeft80  = 0x840F
+	sprmPDxaLeft180 = 0x8411
+	sprmPFInTable   = 0x2416
+	sprmPFTtp       = 0x2417
+	sprmPOutLvl     = 0x2640
+	sprmPItap       = 0x6649
+	sprmPFInnerTtp  = 0x244C
+	sprmPJc         = 0x2461
+	sprmPDxaLeft    = 0x845E
+	sprmPDxaLeft1   = 0x8460
+	sprmPChgTabs    = 0xC615
+	sprmTDefTable   = 0xD608
+	sprmTDefTable10 = 0xD606
+)
+
+// prl is a single property modifier and its operand (section 2.2.5.2)
+type prl struct {
+	sprm    uint16
+	operand []byte
+}
+
+// sgc returns the kind of content the sprm modifies: 1 paragraph, 2 character, 3 picture, 4 section, 5 table
+func (p prl) sgc() int {
+	return int(p.sprm>>10) & 7
+}
+
+// spra returns the size class of the operand (section 2.2.5.1)
+func (p prl) spra() int {
+	return int(p.sprm >> 13)
+}
+
+func (p prl) byteOperand() int {
+	if len(p.operand) < 1 {
+		return 0
+	}
+	return int(p.operand[0])
+}
+
+func (p prl) wordOperand() int {
+	if len(p.operand) < 2 {
+		return 0
+	}
+	return getInt16(p.operand, 0)
+}
+
+func (p prl) longOperand() int {
+	if len(p.operand) < 4 {
+		return 0
+	}
+	return getInt(p.operand, 0)
+}
+
+// parse an array of Prl elements (grpprl). Parsing stops at the first truncated Prl
+func parseGrpprl(grpprl []byte) []prl {
+	var prls []prl
+	for i := 0; i+2 <= len(grpprl); {
+		sprm := binary.LittleEndian.Uint16(grpprl[i : i+2])
+		i += 2
+		start, size := sprmOperandSize(sprm, grpprl[i:])
+		if size < 0 || i+start+size > len(grpprl) {
+			break
+		}
+		prls = append(prls, prl{sprm: sprm, operand: grpprl[i+start : i+start+size]})
+		i += start + size
+	}
+	return prls
+}
+
+// find the operand of a sprm. Returns the number of length bytes to skip before the operand and
+// the size of the operand, or a negative size if the operand cannot be determined
+func sprmOperandSize(sprm uint16, b []byte) (int, int) {
+	switch sprm >> 13 {
+	case 0, 1:
+		return 0, 1
+	case 2, 4, 5:
+		return 0, 2
+	case 3:
+		return 0, 4
+	case 7:
+		return 0, 3
+	}
+
+	switch sprm {
+	case sprmTDefTable, sprmTDefTable10: // cb is 2 bytes and is incremented by 1 (section 2.9.321)
+		if len(b) < 2 {
+			return 0, -1
+		}
+		return 2, getInt16(b, 0) - 1
+	case sprmPChgTabs: // cb of 255 means the size is calculated from the tab counts (section 2.9.182)
+		if len(b) < 1 {
+			return 0, -1
+		}
+		if b[0] != 255 {
+			return 1, int(b[0])
+		}
+		if len(b) < 2 {
+			return 0, -1
+		}
+		size := 1 + int(b[1])*4
+		if len(b) < 1+size+1 {
+			return 0, -1
+		}
+		return 1, size + 1 + int(b[1+size])*3
+	}
+	if len(b) < 1 {
+		return 0, -1
+	}
+	return 1, int(b[0])
+}
@@ -0,0 +1,37 @@
+package doc2txt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseGrpprl(t *testing.T) {
+	if prls := parseGrpprl(nil); len(prls) != 0 {
+		t.Error("expected no prls", prls)
+	}
+
+	// sprmCFBold (toggle), sprmCHps (2 bytes), sprmCCv (4 bytes), sprmPItap (4 bytes)
+	grpprl := []byte{0x35, 0x08, 0x01, 0x43, 0x4A, 0x18, 0x00, 0x70, 0x68, 0xFF, 0x00, 0x00, 0x00, 0x49, 0x66, 0x01, 0x00, 0x00, 0x00}
+	prls := parseGrpprl(grpprl)
+	if len(prls) != 4 || prls[0].sprm != sprmCFBold || prls[0].byteOperand() != 1 || prls[1].sprm != sprmCHps || prls[1].wordOperand() != 24 ||
+		prls[2].sprm != sprmCCv || prls[2].longOperand() != 0xFF || prls[3].sprm != sprmPItap || prls[3].longOperand() != 1 {
+		t.Error("expected 4 valid prls", prls)
+	}
+	if prls[0].sgc() != 2 || prls[3].sgc() != 1 || prls[1].spra() != 2 {
+		t.Error("expected correct sgc and spra", prls[0].sgc(), prls[3].sgc(), prls[1].spra())
+	}
+
+	// variable length operand followed by a truncated prl
+	grpprl = []byte{0x09, 0xCA, 0x02, 0xAA, 0xBB, 0x43, 0x4A, 0x18}
+	prls = parseGrpprl(grpprl)
+	if len(prls) != 1 || !bytes.Equal(prls[0].operand, []byte{0xAA, 0xBB}) {
+		t.Error("expected variable length operand and no truncated prl", prls)
+	}
+
+	// sprmTDefTable has a 2 byte size which is one larger than the operand
+	grpprl = []byte{0x08, 0xD6, 0x03, 0x00, 0x01, 0x02, 0x35, 0x08, 0x00}
+	prls = parseGrpprl(grpprl)
+	if len(prls) != 2 || !bytes.Equal(prls[0].operand, []byte{0x01, 0x02}) || prls[1].sprm != sprmCFBold {
+		t.Error("expected sprmTDefTable operand", prls)
+	}
+}
@@ -0,0 +1,511 @@
+package doc2txt
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// docBuilder walks the characters of each story and assembles the Document
+type docBuilder struct {
+	w       *wordFile
+	doc     *Document
+	text    []uint16
+	stsh    *stsh
+	papx    []fkpRun
+	runs    []cpRun
+	run     int // index of the current run in runs
+	authors []string
+	fonts   []ffn
+	fields  []*openField
+}
+
+// cpRun is a range of CPs that share the same direct character formatting
+type cpRun struct {
+	cpStart int
+	cpEnd   int
+	grpprl  []byte
+}
+
+// a field whose end character has not been reached yet
+type openField struct {
+	index       int
+	inResult    bool
+	instruction []uint16
+	result      []uint16
+}
+
+// storyPart describes where the text and fields of a document part are found
+type storyPart struct {
+	typ    StoryType
+	ccp    int
+	fcFld  int
+	lcbFld int
+}
+
+func getDocument(w *wordFile) (*Document, error) {
+	text, err := getCharacters(w.wordDoc, w.clx)
+	if err != nil {
+		return nil, err
+	}
+	stsh, err := getStsh(w.table, w.fib)
+	if err != nil {
+		return nil, err
+	}
+	chpx, err := getChpxRuns(w.wordDoc, w.table, w.fib)
+	if err != nil {
+		return nil, err
+	}
+	papx, err := getPapxRuns(w.wordDoc, w.table, w.fib)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := getRevisionAuthors(w.table, w.fib)
+	if err != nil {
+		return nil, err
+	}
+	fonts, err := getFonts(w.table, w.fib)
+	if err != nil {
+		return nil, err
+	}
+	lists, instances, err := getLists(w.table, w.fib)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &docBuilder{w: w, text: text, stsh: stsh, papx: papx, runs: getCPRuns(w.clx, chpx), authors: authors, fonts: fonts}
+	b.doc = &Document{SchemaVersion: JSONSchemaVersion, Metadata: getMetadata(w), Fonts: b.modelFonts(), Styles: b.styles()}
+	b.lists(lists, instances)
+	if err := b.stories(); err != nil {
+		return nil, err
+	}
+	if err := b.comments(); err != nil {
+		return nil, err
+	}
+	if err := b.notes(); err != nil {
+		return nil, err
+	}
+	if err := b.shapes(); err != nil {
+		return nil, err
+	}
+	b.objects()
+	return b.doc, nil
+}
+
+// map the FC based runs from the ChpxFkps onto CPs using the piece table. The properties in
+// the Prm of each piece are applied after those of the Chpx (section 2.4.6.2)
+func getCPRuns(clx *clx, chpx []fkpRun) []cpRun {
+	var runs []cpRun
+	aCP := clx.pcdt.PlcPcd.aCP
+	for i, pcd := range clx.pcdt.PlcPcd.aPcd {
+		size, fcStart := 2, pcd.fc.fc
+		if pcd.fc.fCompressed {
+			size, fcStart = 1, pcd.fc.fc/2
+		}
+		fcEnd := fcStart + size*(aCP[i+1]-aCP[i])
+		prm := clx.prmGrpprl(pcd.prm)
+
+		cp := aCP[i]
+		for j := findFkpIndex(chpx, fcStart); j < len(chpx) && chpx[j].fcStart < fcEnd; j++ {
+			start, end := chpx[j].fcStart, chpx[j].fcEnd
+			if start < fcStart {
+				start = fcStart
+			}
+			if end > fcEnd {
+				end = fcEnd
+			}
+			cpStart, cpEnd := aCP[i]+(start-fcStart)/size, aCP[i]+(end-fcStart)/size
+			if cpStart > cp { // text without a Chpx has no direct formatting
+				runs = append(runs, cpRun{cpStart: cp, cpEnd: cpStart, grpprl: prm})
+			}
+			if cpEnd > cpStart {
+				runs = append(runs, cpRun{cpStart: cpStart, cpEnd: cpEnd, grpprl: appendGrpprl(chpx[j].grpprl, prm)})
+			}
+			cp = cpEnd
+		}
+		if cp < aCP[i+1] {
+			runs = append(runs, cpRun{cpStart: cp, cpEnd: aCP[i+1], grpprl: prm})
+		}
+	}
+	return runs
+}
+
+// join two grpprls into a new one, so the second is applied after the first
+func appendGrpprl(grpprl, more []byte) []byte {
+	if len(more) == 0 {
+		return grpprl
+	}
+	return append(append([]byte{}, grpprl...), more...)
+}
+
+func findFkpIndex(runs []fkpRun, fc int) int {
+	return sort.Search(len(runs), func(i int) bool { return runs[i].fcEnd > fc })
+}
+
+func (b *docBuilder) modelFonts() []Font {
+	var fonts []Font
+	for _, f := range b.fonts {
+		fonts = append(fonts, Font{Name: f.name, AltName: f.altName, Charset: f.charset,
+			Family: fontFamilies[f.family], Pitch: fontPitches[f.pitch], TrueType: f.trueType})
+	}
+	return fonts
+}
+
+// the name of the font at ftc, or an empty string if there is no such font
+func (b *docBuilder) fontName(ftc int) string {
+	if ftc >= 0 && ftc < len(b.fonts) {
+		return b.fonts[ftc].name
+	}
+	return ""
+}
+
+func (b *docBuilder) styles() []Style {
+	var styles []Style
+	for istd, st := range b.stsh.styles {
+		if st.name == "" {
+			continue
+		}
+		s := Style{ID: istd, Name: st.name, BasedOn: b.stsh.name(st.istdBase)}
+		switch st.stk {
+		case stkParagraph:
+			s.Type = "paragraph"
+		case stkCharacter:
+			s.Type = "character"
+		case stkTable:
+			s.Type = "table"
+		case stkNumbering:
+			s.Type = "numbering"
+		}
+		styles = append(styles, s)
+	}
+	return styles
+}
+
+func (b *docBuilder) storyParts() []storyPart {
+	lw, fc := b.w.fib.fibRgLw, b.w.fib.fibRgFcLcb
+	return []storyPart{
+		{StoryMain, lw.ccpText, fc.fcPlcfFldMom, fc.lcbPlcfFldMom},
+		{StoryFootnotes, lw.ccpFtn, fc.fcPlcfFldFtn, fc.lcbPlcfFldFtn},
+		{StoryHeaders, lw.ccpHdd, fc.fcPlcfFldHdr, fc.lcbPlcfFldHdr},
+		{"", lw.ccpMcr, 0, 0}, // reserved, but counted in the CPs (section 2.5.4)
+		{StoryComments, lw.ccpAtn, fc.fcPlcfFldAtn, fc.lcbPlcfFldAtn},
+		{StoryEndnotes, lw.ccpEdn, fc.fcPlcfFldEdn, fc.lcbPlcfFldEdn},
+		{StoryTextboxes, lw.ccpTxbx, fc.fcPlcfFldTxbx, fc.lcbPlcfFldTxbx},
+		{StoryHeaderTextboxes, lw.ccpHdrTxbx, fc.fcPlcffldHdrTxbx, fc.lcbPlcffldHdrTxbx},
+	}
+}
+
+func (b *docBuilder) stories() error {
+	start := 0
+	for _, part := range b.storyParts() {
+		end := start + part.ccp
+		if end > len(b.text) {
+			end = len(b.text)
+		}
+		if part.typ == "" || end <= start {
+			start = end
+			continue
+		}
+
+		fields, err := getPlcFld(b.w.table, part.fcFld, part.lcbFld)
+		if err != nil {
+			return err
+		}
+		bounds, err := b.sectionBounds(part.typ, start, end)
+		if err != nil {
+			return err
+		}
+
+		story := Story{Type: part.typ, CP: start, Length: end - start}
+		b.fields = nil
+		for i := 0; i+1 < len(bounds); i++ {
+			story.Sections = append(story.Sections, Section{CP: bounds[i], Length: bounds[i+1] - bounds[i],
+				Blocks: b.blocks(part.typ, start, bounds[i], bounds[i+1], fields)})
+		}
+		b.doc.Stories = append(b.doc.Stories, story)
+		start = end
+	}
+	return nil
+}
+
+// find the CPs where sections begin using the PlcfSed. Only the main story has sections (section 2.8.26)
+func (b *docBuilder) sectionBounds(typ StoryType, start, end int) ([]int, error) {
+	bounds := []int{start}
+	if typ == StoryMain {
+		sed, err := getPlc(b.w.table, b.w.fib.fibRgFcLcb.fcPlcfSed, b.w.fib.fibRgFcLcb.lcbPlcfSed, 12)
+		if err != nil {
+			return nil, err
+		}
+		for _, cp := range sed.aCP {
+			if cp > bounds[len(bounds)-1] && cp < end {
+				bounds = append(bounds, cp)
+			}
+		}
+	}
+	return append(bounds, end), nil
+}
+
+// build the paragraphs and tables of the CPs from start to end
+func (b *docBuilder) blocks(typ StoryType, storyStart, start, end int, fields *plcFld) []Block {
+	blocks := []Block{}
+	var table *Table
+	var row *TableRow
+	var cell *TableCell
+	flushTable := func() {
+		if table == nil {
+			return
+		}
+		if cell != nil && len(cell.Paragraphs) > 0 { // unterminated cell at the end of the range
+			row.Cells = append(row.Cells, *cell)
+		}
+		if row != nil && len(row.Cells) > 0 {
+			table.Rows = append(table.Rows, *row)
+		}
+		blocks = append(blocks, Block{Type: BlockTable, Table: table})
+		table, row, cell = nil, nil, nil
+	}
+
+	paraStart := start
+	for cp := start; cp < end; cp++ {
+		c := b.text[cp]
+		if c != 0x0D && c != 0x07 && cp != end-1 {
+			continue
+		}
+		p := b.paragraphProperties(cp)
+		para := b.paragraph(typ, storyStart, paraStart, cp, p, fields)
+		paraStart = cp + 1
+
+		if !p.fInTable {
+			flushTable()
+			blocks = append(blocks, Block{Type: BlockParagraph, Paragraph: para})
+			continue
+		}
+		if table == nil {
+			table = &Table{}
+		}
+		if row == nil {
+			row = &TableRow{}
+		}
+		if p.fTtp { // end of row mark (section 2.4.3)
+			if cell != nil && len(cell.Paragraphs) > 0 {
+				row.Cells = append(row.Cells, *cell)
+			}
+			table.Rows = append(table.Rows, *row)
+			row, cell = nil, nil
+			continue
+		}
+		if cell == nil {
+			cell = &TableCell{}
+		}
+		cell.Paragraphs = append(cell.Paragraphs, *para)
+		if c == 0x07 {
+			row.Cells = append(row.Cells, *cell)
+			cell = nil
+		}
+	}
+	flushTable()
+	return blocks
+}
+
+// find the paragraph properties using the PAPX for the paragraph mark at cp, followed by the
+// Prm of the piece that holds the paragraph mark (section 2.4.6.1)
+func (b *docBuilder) paragraphProperties(cp int) pap {
+	fc, _, ok := b.w.clx.fcForCP(cp)
+	if !ok {
+		return b.stsh.pap(istdNormal)
+	}
+	run := findFkpRun(b.papx, fc)
+	if run == nil {
+		p := b.stsh.pap(istdNormal)
+		p.apply(b.w.clx.prmGrpprlForCP(cp))
+		return p
+	}
+	p := b.stsh.pap(run.istd)
+	p.apply(appendGrpprl(run.grpprl, b.w.clx.prmGrpprlForCP(cp)))
+	return p
+}
+
+// build a paragraph from the CPs from start up to the paragraph mark at end
+func (b *docBuilder) paragraph(typ StoryType, storyStart, start, end int, p pap, fields *plcFld) *Paragraph {
+	para := &Paragraph{CP: start, Style: b.stsh.name(p.istd), Alignment: alignment(p.jc), List: b.listItem(p)}
+	base := b.stsh.chp(p.istd)
+
+	var text []uint16
+	var run *Run
+	var runChp chp
+	var runText []uint16
+	var runEnd int
+	flushRun := func() {
+		if run == nil {
+			return
+		}
+		run.Text = string(utf16.Decode(runText))
+		para.Runs = append(para.Runs, *run)
+		b.addRevision(typ, run, runEnd, runChp)
+		if !run.Deleted {
+			text = append(text, runText...)
+		}
+		run, runText = nil, nil
+	}
+
+	for cp := start; cp <= end; cp++ {
+		c := b.text[cp]
+		if b.fieldCharacter(c, cp, storyStart, typ, fields) {
+			continue
+		}
+		if cp == end && (c == 0x0D || c == 0x07 || c == 0x0C) { // paragraph marks belong to the fields they are in
+			b.addFieldText('\n')
+			break
+		}
+		if b.inInstruction() {
+			b.addFieldText(c)
+			continue
+		}
+		if c == 0x01 {
+			b.addPicture(cp, b.characterProperties(cp, base))
+		}
+		u, ok := modelCharacter(c)
+		if !ok {
+			continue
+		}
+		ch := b.characterProperties(cp, base)
+		chars := fontCharacter(b.fonts, ch.ftc, u)
+		if c == 0x28 && ch.symbol {
+			chars = fontCharacter(b.fonts, ch.symbolFtc, ch.symbolChar)
+		}
+		for _, u := range chars {
+			b.addFieldText(u)
+		}
+
+		if run == nil || ch != runChp {
+			flushRun()
+			run, runChp = b.newRun(cp, ch), ch
+		}
+		runText = append(runText, chars...)
+		runEnd = cp + 1
+	}
+	flushRun()
+	para.Text = string(utf16.Decode(text))
+	return para
+}
+
+func (b *docBuilder) characterProperties(cp int, base chp) chp {
+	for b.run < len(b.runs) && b.runs[b.run].cpEnd <= cp {
+		b.run++
+	}
+	for b.run > 0 && (b.run == len(b.runs) || b.runs[b.run].cpStart > cp) {
+		b.run--
+	}
+	c := base
+	if b.run < len(b.runs) && b.runs[b.run].cpStart <= cp && cp < b.runs[b.run].cpEnd {
+		c.apply(b.runs[b.run].grpprl, base, b.stsh)
+	}
+	return c
+}
+
+func (b *docBuilder) newRun(cp int, c chp) *Run {
+	r := &Run{CP: cp, Bold: c.bold, Italic: c.italic, Underline: c.underline != 0, Strike: c.strike || c.dStrike,
+		SmallCaps: c.smallCaps, Caps: c.caps, Hidden: c.hidden, Font: b.fontName(c.ftc), Size: float64(c.hps) / 2, Color: c.color(),
+		Inserted: c.rmarkIns, Deleted: c.rmarkDel}
+	switch c.iss {
+	case 1:
+		r.VerticalAlign = "superscript"
+	case 2:
+		r.VerticalAlign = "subscript"
+	}
+	return r
+}
+
+// map a character to the text used in the document model. Returns false for characters
+// that have no text such as object anchors and note references
+func modelCharacter(c uint16) (uint16, bool) {
+	switch c {
+	case 0x09:
+		return c, true
+	case 0x0B: // line break
+		return '\n', true
+	case 0x0C: // page break
+		return '\f', true
+	case 0x1E: // non-breaking hyphen
+		return 0x2011, true
+	case 0x1F: // optional hyphen
+		return 0x00AD, true
+	}
+	return c, c >= 0x20
+}
+
+// paragraph alignment from the jc value of sprmPJc (section 2.6.2)
+func alignment(jc int) string {
+	switch jc {
+	case 1:
+		return "center"
+	case 2:
+		return "right"
+	case 3:
+		return "justify"
+	case 4, 5:
+		return "distribute"
+	}
+	return ""
+}
+
+// handle field begin, separator and end characters (section 2.8.25). Returns true if c is one of them
+func (b *docBuilder) fieldCharacter(c uint16, cp, storyStart int, typ StoryType, fields *plcFld) bool {
+	switch c {
+	case 0x13:
+		b.doc.Fields = append(b.doc.Fields, Field{Story: typ, CP: cp, Type: fields.fieldType(cp - storyStart)})
+		b.fields = append(b.fields, &openField{index: len(b.doc.Fields) - 1})
+	case 0x14:
+		if len(b.fields) > 0 {
+			b.fields[len(b.fields)-1].inResult = true
+		}
+	case 0x15:
+		if len(b.fields) == 0 {
+			return true
+		}
+		f := b.fields[len(b.fields)-1]
+		b.fields = b.fields[:len(b.fields)-1]
+		field := &b.doc.Fields[f.index]
+		field.Instruction = strings.TrimSpace(string(utf16.Decode(f.instruction)))
+		field.Result = strings.TrimRight(string(utf16.Decode(f.result)), "\n")
+		if field.Type == "" {
+			if words := strings.Fields(field.Instruction); len(words) > 0 {
+				field.Type = strings.ToUpper(words[0])
+			}
+		}
+		if field.Type == "EQ" {
+			field.Equation = eqFieldToLaTeX(field.Instruction)
+		}
+		if len(b.fields) > 0 { // a nested field's result is part of the outer field
+			b.fields[len(b.fields)-1].appendText(utf16.Encode([]rune(field.Result)))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// true if the current character is part of a field instruction, which is not displayed
+func (b *docBuilder) inInstruction() bool {
+	for _, f := range b.fields {
+		if !f.inResult {
+			return true
+		}
+	}
+	return false
+}
+
+// add a character to the innermost open field
+func (b *docBuilder) addFieldText(c uint16) {
+	if len(b.fields) > 0 {
+		b.fields[len(b.fields)-1].appendText([]uint16{c})
+	}
+}
+
+func (f *openField) appendText(u []uint16) {
+	if f.inResult {
+		f.result = append(f.result, u...)
+	} else {
+		f.instruction = append(f.instruction, u...)
+	}
+}
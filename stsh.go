@@ -0,0 +1,188 @@
+package doc2txt
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/richardlehane/mscfb"
+)
+
+var (
+	errInvalidStsh = errors.New("invalid style sheet (STSH) structure")
+)
+
+const (
+	istdNormal      = 0      // Normal paragraph style (section 2.9.271)
+	istdDefaultChar = 10     // Default Paragraph Font character style
+	istdNil         = 0x0FFF // no base style
+	maxStyleDepth   = 11     // guard against loops in the style inheritance tree
+)
+
+// style types (stk) from StdfBase (section 2.9.260)
+const (
+	stkParagraph = 1
+	stkCharacter = 2
+	stkTable     = 3
+	stkNumbering = 4
+)
+
+type stsh struct {
+	ftcAsci int
+	styles  []style // indexed by istd. Empty style definitions have an empty name
+}
+
+type style struct {
+	name     string
+	sti      int
+	stk      int
+	istdBase int
+	papx     []byte
+	chpx     []byte
+}
+
+// read the STSH from the table stream (section 2.9.271)
+func getStsh(table *mscfb.File, fib *fib) (*stsh, error) {
+	if table == nil || fib == nil {
+		return nil, errInvalidArgument
+	}
+	b, err := readBlock(table, fib.fibRgFcLcb.fcStshf, fib.fibRgFcLcb.lcbStshf)
+	if err != nil {
+		return nil, err
+	}
+	return parseStsh(b)
+}
+
+func parseStsh(b []byte) (*stsh, error) {
+	if len(b) == 0 {
+		return &stsh{}, nil
+	}
+	if len(b) < 2 {
+		return nil, errInvalidStsh
+	}
+
+	// LPStshi (section 2.9.136). Stshif is the first 18 bytes of the Stshi
+	cbStshi := getInt16(b, 0)
+	if cbStshi < 18 || 2+cbStshi > len(b) {
+		return nil, errInvalidStsh
+	}
+	cstd := getInt16(b, 2)
+	cbSTDBaseInFile := getInt16(b, 4)
+	s := &stsh{ftcAsci: getInt16(b, 14), styles: make([]style, 0, cstd)}
+
+	offset := 2 + cbStshi
+	for i := 0; i < cstd && offset+2 <= len(b); i++ {
+		cbStd := getInt16(b, offset) // LPStd (section 2.9.135)
+		offset += 2
+		if offset+cbStd > len(b) {
+			return nil, errInvalidStsh
+		}
+		s.styles = append(s.styles, parseStd(b[offset:offset+cbStd], cbSTDBaseInFile))
+		offset += cbStd
+		offset += offset % 2 // LPStd structures are stored on even-byte boundaries
+	}
+	return s, nil
+}
+
+// parse STD (section 2.9.258). Style definitions that cannot be read are treated as empty
+func parseStd(b []byte, cbSTDBaseInFile int) style {
+	if len(b) < 10 || cbSTDBaseInFile > len(b) {
+		return style{}
+	}
+
+	// StdfBase (section 2.9.260)
+	st := style{
+		sti:      getInt16(b, 0) & 0x0FFF,
+		stk:      getInt16(b, 2) & 0x000F,
+		istdBase: getInt16(b, 2) >> 4,
+	}
+	cupx := getInt16(b, 4) & 0x000F
+
+	name, n := parseXst(b[cbSTDBaseInFile:]) // xstzName is an Xstz (section 2.9.354)
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		name = name[:i] // drop any aliases
+	}
+	st.name = name
+	offset := cbSTDBaseInFile + n + 2
+
+	// GrLPUpxSw (section 2.9.113): the formatting sets depend on the style type
+	var upx [][]byte
+	for i := 0; i < cupx && offset+2 <= len(b); i++ {
+		cbUpx := getInt16(b, offset)
+		offset += 2
+		if offset+cbUpx > len(b) {
+			break
+		}
+		upx = append(upx, b[offset:offset+cbUpx])
+		offset += cbUpx + cbUpx%2
+	}
+	switch {
+	case st.stk == stkParagraph && len(upx) >= 2:
+		if len(upx[0]) >= 2 {
+			st.papx = upx[0][2:] // skip the istd in UpxPapx
+		}
+		st.chpx = upx[1]
+	case st.stk == stkCharacter && len(upx) >= 1:
+		st.chpx = upx[0]
+	case st.stk == stkTable && len(upx) >= 3:
+		if len(upx[1]) >= 2 {
+			st.papx = upx[1][2:]
+		}
+		st.chpx = upx[2]
+	}
+	return st
+}
+
+func (s *stsh) style(istd int) *style {
+	if s == nil || istd < 0 || istd >= len(s.styles) || s.styles[istd].name == "" {
+		return nil
+	}
+	return &s.styles[istd]
+}
+
+// the name of the style at istd, or an empty string if there is no such style
+func (s *stsh) name(istd int) string {
+	if st := s.style(istd); st != nil {
+		return st.name
+	}
+	return ""
+}
+
+// resolve the paragraph properties of a paragraph style, including its base styles
+func (s *stsh) pap(istd int) pap {
+	p := pap{istd: istd}
+	s.applyPapx(&p, istd, 0)
+	p.istd = istd
+	return p
+}
+
+func (s *stsh) applyPapx(p *pap, istd int, depth int) {
+	st := s.style(istd)
+	if st == nil || depth > maxStyleDepth {
+		return
+	}
+	if st.istdBase != istdNil {
+		s.applyPapx(p, st.istdBase, depth+1)
+	}
+	p.apply(st.papx)
+}
+
+// resolve the character properties of a paragraph style, including its base styles
+func (s *stsh) chp(istd int) chp {
+	c := defaultChp()
+	if s != nil {
+		c.ftc = s.ftcAsci
+	}
+	s.applyChpx(&c, istd, 0)
+	return c
+}
+
+func (s *stsh) applyChpx(c *chp, istd int, depth int) {
+	st := s.style(istd)
+	if st == nil || depth > maxStyleDepth {
+		return
+	}
+	if st.istdBase != istdNil {
+		s.applyChpx(c, st.istdBase, depth+1)
+	}
+	c.apply(st.chpx, *c, nil)
+}
@@ -0,0 +1,112 @@
+package doc2txt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+func TestSymbolCharacter(t *testing.T) {
+	tests := []struct {
+		font     string
+		c        uint16
+		expected rune
+		ok       bool
+	}{
+		{"Symbol", 0xF0B7, 0x2022, true}, // bullet
+		{"Symbol", 0x61, 0x03B1, true},   // alpha
+		{"SYMBOL", 0x28, '(', true},
+		{"Symbol", 0xF080, 0, false},
+		{"Wingdings", 0xF0A7, 0x25AA, true},  // small square bullet
+		{"Wingdings", 0xF0FE, 0x1F5F9, true}, // checked box
+		{"Wingdings", 0x6F, 0x25A1, true},    // empty box
+		{"Wingdings", 0xF0FF, 0, false},
+		{"Wingdings", 0x0D, 0, false},
+		{"Webdings", 0xF061, 0x2714, true}, // check mark
+		{"Webdings", 0x7F, 0, false},
+		{"Arial", 0xF0B7, 0, false},
+	}
+	for _, test := range tests {
+		if r, ok := symbolCharacter(test.font, test.c); r != test.expected || ok != test.ok {
+			t.Errorf("expected %U for %#x in %s, got %U", test.expected, test.c, test.font, r)
+		}
+	}
+}
+
+func TestFontCharacter(t *testing.T) {
+	fonts := []ffn{{name: "Times New Roman"}, {name: "Wingdings", charset: symbolCharset}}
+	if s := string(utf16.Decode(fontCharacter(fonts, 1, 0xF0FE))); s != "\U0001F5F9" {
+		t.Errorf("expected checked box, got %q", s)
+	}
+	if s := fontCharacter(fonts, 0, 0xF0FE); len(s) != 1 || s[0] != 0xF0FE {
+		t.Error("expected characters in other fonts to be unchanged", s)
+	}
+	if s := fontCharacter(fonts, 5, 'a'); len(s) != 1 || s[0] != 'a' {
+		t.Error("expected characters in unknown fonts to be unchanged", s)
+	}
+}
+
+// open simpleDoc.doc with its third character changed to 0x28 and a Prc that makes it a symbol
+// from the font at ftc
+func symbolTestDoc(t *testing.T, ftc int, xchar uint16) *wordFile {
+	b, err := ioutil.ReadFile(`testData/simpleDoc.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := &memoryFile{b: b}
+	doc, _ := mscfb.New(mem)
+	wordDoc, _, _ := getWordDocAndTables(doc)
+	wordDoc.WriteAt([]byte{0x28}, 2050)
+
+	w, err := openWordFile(bytes.NewReader(mem.b), &options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbol := make([]byte, 6)
+	binary.LittleEndian.PutUint16(symbol, sprmCSymbol)
+	binary.LittleEndian.PutUint16(symbol[2:], uint16(ftc))
+	binary.LittleEndian.PutUint16(symbol[4:], xchar)
+	w.clx.rgPrc = [][]byte{symbol}
+	w.clx.pcdt.PlcPcd.aPcd[0].prm = 1 // Prm1 for the first Prc
+	return w
+}
+
+func TestSymbols(t *testing.T) {
+	tests := []struct {
+		font     string
+		xchar    uint16
+		expected string
+	}{
+		{"Symbol", 0xF061, "12α45"},
+		{"Symbol", 0xF0A5, "12∞45"},
+		{"Times New Roman", 0x03A9, "12Ω45"},
+	}
+	for _, test := range tests {
+		w := symbolTestDoc(t, 0, test.xchar)
+		fonts, _ := getFonts(w.table, w.fib)
+		for i, f := range fonts {
+			if f.name == test.font {
+				w = symbolTestDoc(t, i, test.xchar)
+			}
+		}
+
+		text, err := getText(w, getOptions(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s, _ := ioutil.ReadAll(text); string(s) != test.expected+"\r" {
+			t.Errorf("expected %q, got %q", test.expected, s)
+		}
+		d, err := getDocument(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := d.Stories[0].Sections[0].Blocks[0].Paragraph; p == nil || p.Text != test.expected {
+			t.Error("expected symbol in the document model", p)
+		}
+	}
+}
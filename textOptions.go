@@ -0,0 +1,85 @@
+package doc2txt
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Newline is the line ending that ParseDoc writes at the end of each paragraph and line
+type Newline string
+
+// The line endings
+const (
+	NewlineCR   Newline = "\r" // the paragraph mark that Word uses, which is the default
+	NewlineLF   Newline = "\n"
+	NewlineCRLF Newline = "\r\n"
+)
+
+// TextOptions change how ParseDoc writes the text. The zero value writes the text as it is in
+// the document, with each paragraph ending in \r
+type TextOptions struct {
+	Newline            Newline // the line ending written for \r, \n and \r\n, or empty to leave them as they are
+	CollapseBlankLines bool    // write at most one blank line in a row
+	TrimTrailingSpace  bool    // remove the white space at the end of each line
+	NormalizeNFC       bool    // normalize the text to Unicode Normalization Form C
+	ASCIIPunctuation   bool    // replace curly quotes, dashes and ellipses with ASCII characters
+}
+
+// the ASCII replacements for typographic punctuation
+var asciiPunctuation = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`,
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "−", "-",
+	"—", "--", "―", "--",
+	"…", "...",
+)
+
+// apply the options to text
+func (t TextOptions) render(text string) string {
+	if t.NormalizeNFC {
+		text = norm.NFC.String(text)
+	}
+	if t.ASCIIPunctuation {
+		text = asciiPunctuation.Replace(text)
+	}
+	if t.Newline == "" && !t.CollapseBlankLines && !t.TrimTrailingSpace {
+		return text
+	}
+
+	var b strings.Builder
+	blank := false
+	for len(text) > 0 {
+		line, newline := text, ""
+		if i := strings.IndexAny(text, "\r\n"); i >= 0 {
+			line, newline = text[:i], text[i:i+1]
+			if strings.HasPrefix(text[i:], "\r\n") {
+				newline = "\r\n"
+			}
+		}
+		text = text[len(line)+len(newline):]
+
+		if t.TrimTrailingSpace {
+			line = strings.TrimRightFunc(line, isTrailingSpace)
+		}
+		if t.CollapseBlankLines {
+			isBlank := strings.TrimFunc(line, isTrailingSpace) == ""
+			if isBlank && blank {
+				continue
+			}
+			blank = isBlank
+		}
+		if t.Newline != "" && newline != "" {
+			newline = string(t.Newline)
+		}
+		b.WriteString(line)
+		b.WriteString(newline)
+	}
+	return b.String()
+}
+
+// white space that can be removed from the end of a line. Page breaks are kept
+func isTrailingSpace(r rune) bool {
+	return r != '\f' && unicode.IsSpace(r)
+}
@@ -0,0 +1,44 @@
+package doc2txt
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestTextOptions(t *testing.T) {
+	text := "“Quote” – dash — long… \t\r\r \r\rcafe\u0301\r\nline\nend  "
+	tests := []struct {
+		options  TextOptions
+		expected string
+	}{
+		{TextOptions{}, text},
+		{TextOptions{Newline: NewlineLF}, "“Quote” – dash — long… \t\n\n \n\ncafe\u0301\nline\nend  "},
+		{TextOptions{Newline: NewlineCRLF}, "“Quote” – dash — long… \t\r\n\r\n \r\n\r\ncafe\u0301\r\nline\r\nend  "},
+		{TextOptions{TrimTrailingSpace: true}, "“Quote” – dash — long…\r\r\r\rcafe\u0301\r\nline\nend"},
+		{TextOptions{CollapseBlankLines: true}, "“Quote” – dash — long… \t\r\rcafe\u0301\r\nline\nend  "},
+		{TextOptions{NormalizeNFC: true}, "“Quote” – dash — long… \t\r\r \r\rcaf\u00E9\r\nline\nend  "},
+		{TextOptions{ASCIIPunctuation: true}, "\"Quote\" - dash -- long... \t\r\r \r\rcafe\u0301\r\nline\nend  "},
+		{TextOptions{Newline: NewlineLF, CollapseBlankLines: true, TrimTrailingSpace: true, NormalizeNFC: true, ASCIIPunctuation: true},
+			"\"Quote\" - dash -- long...\n\ncaf\u00E9\nline\nend"},
+	}
+	for i, test := range tests {
+		if s := test.options.render(text); s != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, s)
+		}
+	}
+	if s := (TextOptions{TrimTrailingSpace: true}).render("page \f\r"); s != "page \f\r" {
+		t.Errorf("expected page break to be kept, got %q", s)
+	}
+}
+
+func TestParseDocTextOptions(t *testing.T) {
+	f, _ := os.Open(`testData/docFile.doc`)
+	buf, err := ParseDoc(f, WithTextOptions(TextOptions{Newline: NewlineLF}))
+	if err != nil {
+		t.Fatal("expected to be able to parse document", err)
+	}
+	if s := buf.(*bytes.Buffer).String(); s != complicatedDoc {
+		t.Errorf("expected text with LF line endings, got %q", s)
+	}
+}
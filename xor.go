@@ -0,0 +1,233 @@
+package doc2txt
+
+import (
+	"io"
+	"math"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+const maxXorPasswordLength = 15
+
+// the initial values of the XOR key for each password length ([MS-OFFCRYPTO] section 2.3.7.2)
+var xorInitialCode = [maxXorPasswordLength]uint16{
+	0xE1F0, 0x1D0F, 0xCC9C, 0x84C0, 0x110C, 0x0E10, 0xF1CE, 0x313E, 0x1872, 0xE139, 0xD40F, 0x84F9, 0x280C, 0xA96A, 0x4EC3,
+}
+
+// the values combined into the XOR key for each bit of the password, 7 for each character
+var xorMatrix = [maxXorPasswordLength * 7]uint16{
+	0xAEFC, 0x4DD9, 0x9BB2, 0x2745, 0x4E8A, 0x9D14, 0x2A09,
+	0x7B61, 0xF6C2, 0xFDA5, 0xEB6B, 0xC6F7, 0x9DCF, 0x2BBF,
+	0x4563, 0x8AC6, 0x05AD, 0x0B5A, 0x16B4, 0x2D68, 0x5AD0,
+	0x0375, 0x06EA, 0x0DD4, 0x1BA8, 0x3750, 0x6EA0, 0xDD40,
+	0xD849, 0xA0B3, 0x5147, 0xA28E, 0x553D, 0xAA7A, 0x44D5,
+	0x6F45, 0xDE8A, 0xAD35, 0x4A4B, 0x9496, 0x390D, 0x721A,
+	0xEB23, 0xC667, 0x9CEF, 0x29FF, 0x53FE, 0xA7FC, 0x5FD9,
+	0x47D3, 0x8FA6, 0x0F6D, 0x1EDA, 0x3DB4, 0x7B68, 0xF6D0,
+	0xB861, 0x60E3, 0xC1C6, 0x93AD, 0x377B, 0x6EF6, 0xDDEC,
+	0x45A0, 0x8B40, 0x06A1, 0x0D42, 0x1A84, 0x3508, 0x6A10,
+	0xAA51, 0x4483, 0x8906, 0x022D, 0x045A, 0x08B4, 0x1168,
+	0x76B4, 0xED68, 0xCAF1, 0x85C3, 0x1BA7, 0x374E, 0x6E9C,
+	0x3730, 0x6E60, 0xDCC0, 0xA9A1, 0x4363, 0x86C6, 0x1DAD,
+	0x3331, 0x6662, 0xCCC4, 0x89A9, 0x0373, 0x06E6, 0x0DCC,
+	0x1021, 0x2042, 0x4084, 0x8108, 0x1231, 0x2462, 0x48C4,
+}
+
+// the bytes used to fill the XOR array after the password
+var xorPadArray = [maxXorPasswordLength]byte{
+	0xBB, 0xFF, 0xFF, 0xBA, 0xFF, 0xFF, 0xB9, 0x80, 0x00, 0xBE, 0x0F, 0x00, 0xBF, 0x0F, 0x00,
+}
+
+// remove XOR obfuscation (section 2.2.6.1). The XOR array comes from the first password that matches
+// the verifier in lKey. If no passwords are given, the array is recovered from the WordDocument stream
+func deobfuscate(ra io.ReaderAt, fib *fib, wordDoc *mscfb.File, passwords []string) (*mscfb.Reader, error) {
+	if len(passwords) == 0 {
+		if array, ok := recoverXorArray(wordDoc, fib); ok {
+			return transformStreams(ra, fib, 0, xorTransform(array))
+		}
+		return nil, &EncryptedError{Kind: EncryptionXOR}
+	}
+	for _, password := range passwords {
+		p := xorPassword(password)
+		if len(p) > 0 && xorVerifier(p) == uint32(fib.base.lKey) {
+			return transformStreams(ra, fib, 0, xorTransform(xorArray(p)))
+		}
+	}
+	return nil, ErrIncorrectPassword
+}
+
+// XOR each byte with the array, starting from the beginning of the stream ([MS-OFFCRYPTO] section 2.3.7.6).
+// Word leaves the bytes that are zero, and those that the XOR would make zero, as they are
+func xorTransform(array [16]byte) func([]byte) {
+	return func(b []byte) {
+		for i, c := range b {
+			if c != 0 && c != array[i%16] {
+				b[i] = c ^ array[i%16]
+			}
+		}
+	}
+}
+
+// passwords are reduced to one byte per character, using the high byte when the low byte is zero ([MS-OFFCRYPTO] section 2.3.7.4)
+func xorPassword(password string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(password)) {
+		if c&0xFF != 0 {
+			b = append(b, byte(c))
+		} else {
+			b = append(b, byte(c>>8))
+		}
+		if len(b) == maxXorPasswordLength {
+			break
+		}
+	}
+	return b
+}
+
+// the password verifier stored in lKey ([MS-OFFCRYPTO] section 2.3.7.4)
+func xorVerifier(password []byte) uint32 {
+	return uint32(xorKey(password))<<16 | uint32(xorPasswordVerifier(password))
+}
+
+// the XOR key of a password ([MS-OFFCRYPTO] section 2.3.7.2)
+func xorKey(password []byte) uint16 {
+	key := xorInitialCode[len(password)-1]
+	element := len(xorMatrix) - 1
+	for i := len(password) - 1; i >= 0; i-- {
+		c := password[i]
+		for bit := 0; bit < 7; bit++ {
+			if c&0x40 != 0 {
+				key ^= xorMatrix[element]
+			}
+			c <<= 1
+			element--
+		}
+	}
+	return key
+}
+
+// the 16-bit password verifier ([MS-OFFCRYPTO] section 2.3.7.1)
+func xorPasswordVerifier(password []byte) uint16 {
+	var verifier uint16
+	array := append([]byte{byte(len(password))}, password...)
+	for i := len(array) - 1; i >= 0; i-- {
+		verifier = (verifier>>14)&1 | (verifier<<1)&0x7FFF
+		verifier ^= uint16(array[i])
+	}
+	return verifier ^ 0xCE4B
+}
+
+// build the 16 byte XOR array from a password ([MS-OFFCRYPTO] section 2.3.7.2)
+func xorArray(password []byte) [16]byte {
+	key := xorKey(password)
+	high, low := byte(key>>8), byte(key)
+	xorRor := func(b1, b2 byte) byte {
+		v := b1 ^ b2
+		return v>>1 | v<<7
+	}
+
+	var array [16]byte
+	copy(array[:], xorPadArray[:])
+	index := len(password)
+	if index%2 == 1 {
+		array[index] = xorRor(xorPadArray[0], high)
+		index--
+		array[index] = xorRor(password[len(password)-1], low)
+	}
+	for index > 0 {
+		index--
+		array[index] = xorRor(password[index], high)
+		index--
+		array[index] = xorRor(password[index], low)
+	}
+	index = 15
+	for pad := 15 - len(password); pad > 0; {
+		array[index] = xorRor(xorPadArray[pad], high)
+		index--
+		pad--
+		array[index] = xorRor(xorPadArray[pad], low)
+		index--
+		pad--
+	}
+	return array
+}
+
+// recover the XOR array of a document without its password. The high word of lKey is the XOR key,
+// which gives the bytes of the array after the password for each length of password. Each byte of
+// the array that comes from the password is chosen so that the non-zero bytes at its positions in
+// the WordDocument stream deobfuscate to bytes that are common at the positions that are known.
+// Zero bytes are left out, as they are not obfuscated. The array is only used when its password
+// matches the verifier in lKey
+func recoverXorArray(wordDoc *mscfb.File, fib *fib) ([16]byte, bool) {
+	b, err := readBlock(wordDoc, 0, int(wordDoc.Size))
+	if err != nil {
+		return [16]byte{}, false
+	}
+	unencrypted := fibUnencryptedSize
+	if fib.isWord6() {
+		unencrypted = fibWord6UnencryptedSize
+	}
+	var counts [16][256]int // the number of each obfuscated byte at each position of the array
+	for i := unencrypted; i < len(b); i++ {
+		if b[i] != 0 {
+			counts[i%16][b[i]]++
+		}
+	}
+
+	lKey := uint32(fib.base.lKey)
+	key := uint16(lKey >> 16)
+	keyByte := func(i int) byte { // the byte of the key that each byte of the array is made with
+		if i%2 == 0 {
+			return byte(key)
+		}
+		return byte(key >> 8)
+	}
+	plain := func(c, a byte) byte { // the byte that c deobfuscates to
+		if c == a {
+			return c
+		}
+		return c ^ a
+	}
+	for length := 1; length <= maxXorPasswordLength; length++ {
+		var array [16]byte
+		var known [256]int // the number of each deobfuscated byte at the known positions
+		total := 0
+		for i := length; i < 16; i++ {
+			v := xorPadArray[i-length] ^ keyByte(i)
+			array[i] = v>>1 | v<<7
+			for c, n := range counts[i] {
+				known[plain(byte(c), array[i])] += n
+				total += n
+			}
+		}
+		var score [256]float64
+		for c, n := range known {
+			score[c] = math.Log(float64(n+1) / float64(total+256))
+		}
+
+		password := make([]byte, length)
+		for i := range password {
+			best := math.Inf(-1)
+			for a := 0; a < 256; a++ {
+				p := byte(a)<<1 | byte(a)>>7 ^ keyByte(i)
+				if p == 0 {
+					continue
+				}
+				s := 0.0
+				for c, n := range counts[i] {
+					if n > 0 {
+						s += float64(n) * score[plain(byte(c), byte(a))]
+					}
+				}
+				if s > best {
+					best, password[i] = s, p
+				}
+			}
+		}
+		if xorVerifier(password) == lKey {
+			return xorArray(password), true
+		}
+	}
+	return [16]byte{}, false
+}
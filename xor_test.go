@@ -0,0 +1,128 @@
+package doc2txt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/richardlehane/mscfb"
+)
+
+func TestXorArray(t *testing.T) {
+	// the verifier of "password" is given in [MS-OFFCRYPTO], and the other values were computed by hand from section 2.3.7
+	tests := []struct {
+		password, bytes string
+		verifier, key   uint16
+		array           string
+	}{
+		{"password", "70617373776f7264", 0x83AF, 0x147A, "05ba84b386bd0438e0f5c257c2f5e14a"},
+		{"sécret€", "73e963726574ac", 0x8E96, 0xB32C, "af2da7e0a4e34004e9264b26e90556d9"},
+	}
+	for _, test := range tests {
+		p := xorPassword(test.password)
+		if hex.EncodeToString(p) != test.bytes {
+			t.Errorf("%s: expected password bytes %s, got %x", test.password, test.bytes, p)
+		}
+		if v := xorPasswordVerifier(p); v != test.verifier {
+			t.Errorf("%s: expected verifier %04X, got %04X", test.password, test.verifier, v)
+		}
+		if k := xorKey(p); k != test.key {
+			t.Errorf("%s: expected key %04X, got %04X", test.password, test.key, k)
+		}
+		if a := xorArray(p); hex.EncodeToString(a[:]) != test.array {
+			t.Errorf("%s: expected array %s, got %x", test.password, test.array, a)
+		}
+	}
+
+	if p := xorPassword("longer than fifteen characters"); len(p) != maxXorPasswordLength {
+		t.Error("expected password to be truncated", len(p))
+	}
+}
+
+// obfuscate a copy of b with the XOR array of "password" the way Word does, leaving the bytes that
+// are zero or equal to the array byte as they are. Word 6.0 and Word 95 have no fObfuscated flag
+// and keep their structures in the WordDocument stream
+func obfuscateTestDoc(t *testing.T, b []byte, word6 bool) []byte {
+	b = append([]byte{}, b...)
+	mem := &memoryFile{b: b}
+	doc, err := mscfb.New(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordDoc, _, table := getWordDocAndTables(doc)
+	array, _ := hex.DecodeString("05ba84b386bd0438e0f5c257c2f5e14a")
+
+	type stream struct {
+		file        *mscfb.File
+		unencrypted int
+	}
+	flags, streams := byte(0x81), []stream{{wordDoc, 0x44}, {table, 0}, {getStream(doc, "Data"), 0}} // fEncrypted and fObfuscated
+	if word6 {
+		flags, streams = 0x01, []stream{{wordDoc, 0x34}}
+	}
+	b11 := make([]byte, 1)
+	wordDoc.ReadAt(b11, 11)
+	wordDoc.WriteAt([]byte{b11[0] | flags}, 11)
+	wordDoc.WriteAt(uint32Bytes(0x147A83AF), 14) // lKey
+	for _, stream := range streams {
+		content := make([]byte, stream.file.Size)
+		stream.file.ReadAt(content, 0)
+		for i := stream.unencrypted; i < len(content); i++ {
+			if c := content[i] ^ array[i%16]; content[i] != 0 && c != 0 {
+				content[i] = c
+			}
+		}
+		stream.file.WriteAt(content, 0)
+	}
+	return mem.b
+}
+
+func TestDeobfuscate(t *testing.T) {
+	original, err := ioutil.ReadFile(`testData/docFile.doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := ParseDoc(bytes.NewReader(original))
+	expected, _ := ioutil.ReadAll(r)
+
+	doc := obfuscateTestDoc(t, original, false)
+	tests := []struct {
+		name      string
+		passwords []string
+		err       error
+	}{
+		{"password", []string{"password"}, nil},
+		{"second password", []string{"wrong", "password"}, nil},
+		{"recovered without password", nil, nil},
+		{"wrong password", []string{"wrong"}, ErrIncorrectPassword},
+	}
+	for _, test := range tests {
+		r, err := ParseDoc(bytes.NewReader(doc), WithPassword(test.passwords...))
+		if test.err != nil || err != nil {
+			if !errors.Is(err, test.err) {
+				t.Error(test.name, err)
+			}
+			continue
+		}
+		text, _ := ioutil.ReadAll(r)
+		if !bytes.Equal(text, expected) {
+			t.Error("expected obfuscated text to match", test.name)
+		}
+	}
+
+	// the array recovered from the stream is the array of the password
+	d, _ := mscfb.New(bytes.NewReader(doc))
+	wordDoc, _, _ := getWordDocAndTables(d)
+	fib, _ := getFib(wordDoc)
+	if array, ok := recoverXorArray(wordDoc, fib); !ok || array != xorArray(xorPassword("password")) {
+		t.Errorf("expected to recover the XOR array, got %x", array)
+	}
+
+	// an array is only recovered when its password matches the verifier
+	fib.base.lKey ^= 1
+	if array, ok := recoverXorArray(wordDoc, fib); ok {
+		t.Errorf("expected the verifier to reject the array, got %x", array)
+	}
+}